@@ -16,13 +16,63 @@ type API struct {
 	client *ovh.Client
 }
 
+// CloudAPI defines the OVH Cloud operations used by the driver. It is
+// implemented by API and may be replaced by a fake backend
+type CloudAPI interface {
+	GetProjects() (Projects, error)
+	GetProject(projectID string) (*Project, error)
+	GetProjectByName(projectName string) (*Project, error)
+	GetPublicNetworkID(projectID string) (string, error)
+	GetPrivateNetworkByName(projectID, networkName string) (*Network, error)
+	GetRegions(projectID string) (Regions, error)
+	GetRegion(projectID, region string) (*Region, error)
+	GetFlavorByName(projectID, region, flavorName string) (*Flavor, error)
+	GetImageByName(projectID, region, imageName string) (*Image, error)
+	GetSshkeyByName(projectID, region, sshKeyName string) (*Sshkey, error)
+	CreateSshkey(projectID, name, pubkey string) (*Sshkey, error)
+	DeleteSshkey(projectID, sshkeyID string) error
+	CreateInstance(projectID, name, pubkeyID, flavorID, imageID, region string, networks NetworkParams, monthlyBilling bool, userData string) (*Instance, error)
+	GetInstance(projectID, instanceID string) (*Instance, error)
+	RebootInstance(projectID, instanceID string, hard bool) error
+	StartInstance(projectID, instanceID string) error
+	StopInstance(projectID, instanceID string) error
+	ShelveInstance(projectID, instanceID string) error
+	UnshelveInstance(projectID, instanceID string) error
+	DeleteInstance(projectID, instanceID string) error
+	CreateVolume(projectID, region, name, volumeType string, size int) (*Volume, error)
+	GetVolume(projectID, volumeID string) (*Volume, error)
+	AttachVolume(projectID, volumeID, instanceID string) error
+	DetachVolume(projectID, volumeID, instanceID string) error
+	DeleteVolume(projectID, volumeID string) error
+	GetInstances(projectID, region string) (Instances, error)
+	CreatePrivateNetwork(projectID, name string, vlanID int, region string) (*Network, error)
+	GetPrivateNetwork(projectID, networkID string) (*Network, error)
+	DeletePrivateNetwork(projectID, networkID string) error
+	CreateSubnet(projectID, networkID, region, cidr, dhcpStart, dhcpEnd string) (*Subnet, error)
+	GetSubnets(projectID, networkID string) (Subnets, error)
+	GetFailoverIPs(projectID string) (FailoverIPs, error)
+	AttachFailoverIP(projectID, failoverIPID, instanceID string) (*FailoverIP, error)
+	GetSnapshots(projectID, region string) (Images, error)
+	CreateSnapshot(projectID, instanceID, name string) error
+	DeleteSnapshot(projectID, imageID string) error
+	CreateBackupWorkflow(projectID, region, instanceID, name, cron string, rotation int) (*BackupWorkflow, error)
+	DeleteBackupWorkflow(projectID, region, workflowID string) error
+	ResizeInstance(projectID, instanceID, flavorID string) error
+	RescueInstance(projectID, instanceID, imageID string, rescue bool) (*RescueMode, error)
+	ReinstallInstance(projectID, instanceID, imageID string) (*Instance, error)
+	ActivateMonthlyBilling(projectID, instanceID string) (*Instance, error)
+	GetQuotas(projectID string) (Quotas, error)
+}
+
+var _ CloudAPI = (*API)(nil)
+
 // Project is a go representation of a Cloud project
 type Project struct {
 	Name         string `json:"description"`
 	ID           string `json:"project_id"`
 	Unleash      bool   `json:"unleash"`
 	CreationDate string `json:"creationDate"`
-	OrderID      int    `json:"orderID"`
+	OrderID      int    `json:"orderId"`
 	Status       string `json:"status"`
 }
 
@@ -62,18 +112,58 @@ type Images []Image
 // Regions is a list of Cloud Region names
 type Regions []string
 
+// RegionService is the status of a service in a Cloud region
+type RegionService struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// Region is a go representation of a Cloud region
+type Region struct {
+	Name     string          `json:"name"`
+	Status   string          `json:"status"`
+	Services []RegionService `json:"services"`
+}
+
 // Network defines the private network names
 type Network struct {
 	Status string `json:"status"`
 	Name   string `json:"name"`
 	Type   string `json:"type"`
 	ID     string `json:"id"`
-	VlanID int    `json:"vlanid"`
+	VlanID int    `json:"vlanId"`
 }
 
 // Networks is a list of Network
 type Networks []Network
 
+// NetworkReq defines the fields for a private network creation
+type NetworkReq struct {
+	Name    string   `json:"name"`
+	VlanID  int      `json:"vlanId,omitempty"`
+	Regions []string `json:"regions"`
+}
+
+// SubnetReq defines the fields for a private network subnet creation
+type SubnetReq struct {
+	DHCP      bool   `json:"dhcp"`
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	Network   string `json:"network"`
+	NoGateway bool   `json:"noGateway"`
+	Region    string `json:"region"`
+}
+
+// Subnet is a go representation of a private network subnet
+type Subnet struct {
+	ID        string `json:"id"`
+	CIDR      string `json:"cidr"`
+	GatewayIP string `json:"gatewayIp"`
+}
+
+// Subnets is a list of Subnet
+type Subnets []Subnet
+
 // SshkeyReq defines the fields for an SSH Key upload
 type SshkeyReq struct {
 	Name      string `json:"name"`
@@ -87,7 +177,7 @@ type Sshkey struct {
 	ID          string  `json:"id"`
 	PublicKey   string  `json:"publicKey"`
 	Fingerprint string  `json:"fingerPrint"`
-	Regions     Regions `json:"region"`
+	Regions     Regions `json:"regions"`
 }
 
 // Sshkeys is a list of Sshkey
@@ -95,8 +185,10 @@ type Sshkeys []Sshkey
 
 // IP is a go representation of a Cloud IP address
 type IP struct {
-	IP   string `json:"ip"`
-	Type string `json:"type"`
+	IP        string `json:"ip"`
+	Type      string `json:"type"`
+	Version   int    `json:"version"`
+	NetworkID string `json:"networkId"`
 }
 
 // IPs is a list of IPs
@@ -105,6 +197,7 @@ type IPs []IP
 // NetworkParmas for Cloud instance
 type NetworkParam struct {
 	ID string `json:"networkId"`
+	IP string `json:"ip,omitempty"`
 }
 
 type NetworkParams []NetworkParam
@@ -112,27 +205,125 @@ type NetworkParams []NetworkParam
 // InstanceReq defines the fields for a VM creation
 type InstanceReq struct {
 	Name           string        `json:"name"`
-	FlavorID       string        `json:"flavorID"`
-	ImageID        string        `json:"imageID"`
+	FlavorID       string        `json:"flavorId"`
+	ImageID        string        `json:"imageId"`
 	Region         string        `json:"region"`
 	NetworkParams  NetworkParams `json:"networks"`
-	SshkeyID       string        `json:"sshKeyID"`
+	SshkeyID       string        `json:"sshKeyId"`
 	MonthlyBilling bool          `json:"monthlyBilling"`
+	UserData       string        `json:"userData,omitempty"`
 }
 
-// Instance is a go representation of Cloud instance
+// MonthlyBilling is the monthly billing state of an instance
+type MonthlyBilling struct {
+	Since  string `json:"since"`
+	Status string `json:"status"`
+}
+
+// Instance is a go representation of Cloud instance. MonthlyBilling is nil
+// for hourly billed instances
 type Instance struct {
-	Name           string        `json:"name"`
-	ID             string        `json:"id"`
-	Status         string        `json:"status"`
-	Created        string        `json:"created"`
-	Region         string        `json:"region"`
-	NetworkParams  NetworkParams `json:"networks"`
-	Image          Image         `json:"image"`
-	Flavor         Flavor        `json:"flavor"`
-	Sshkey         Sshkey        `json:"sshKey"`
-	IPAddresses    IPs           `json:"ipAddresses"`
-	MonthlyBilling bool          `json:"monthlyBilling"`
+	Name           string          `json:"name"`
+	ID             string          `json:"id"`
+	Status         string          `json:"status"`
+	Created        string          `json:"created"`
+	Region         string          `json:"region"`
+	NetworkParams  NetworkParams   `json:"networks"`
+	Image          Image           `json:"image"`
+	Flavor         Flavor          `json:"flavor"`
+	Sshkey         Sshkey          `json:"sshKey"`
+	IPAddresses    IPs             `json:"ipAddresses"`
+	MonthlyBilling *MonthlyBilling `json:"monthlyBilling"`
+}
+
+// Quota is a go representation of the usage and limits of a project in a region.
+// RAM is in MB
+type Quota struct {
+	Region   string `json:"region"`
+	Instance struct {
+		MaxCores      int `json:"maxCores"`
+		MaxInstances  int `json:"maxInstances"`
+		MaxRAM        int `json:"maxRam"`
+		UsedCores     int `json:"usedCores"`
+		UsedInstances int `json:"usedInstances"`
+		UsedRAM       int `json:"usedRAM"`
+	} `json:"instance"`
+	Volume struct {
+		MaxGigabytes   int `json:"maxGigabytes"`
+		UsedGigabytes  int `json:"usedGigabytes"`
+		MaxVolumeCount int `json:"maxVolumeCount"`
+		VolumeCount    int `json:"volumeCount"`
+	} `json:"volume"`
+}
+
+// Quotas is a list of Quota
+type Quotas []Quota
+
+// FailoverIP is a go representation of a Cloud failover IP
+type FailoverIP struct {
+	ID       string `json:"id"`
+	IP       string `json:"ip"`
+	Block    string `json:"block"`
+	RoutedTo string `json:"routedTo"`
+	Status   string `json:"status"`
+	GeoLoc   string `json:"geoloc"`
+}
+
+// FailoverIPs is a list of FailoverIP
+type FailoverIPs []FailoverIP
+
+// FailoverIPAttachReq defines the fields for a failover IP attach
+type FailoverIPAttachReq struct {
+	InstanceID string `json:"instanceId"`
+}
+
+// Instances is a list of Instance
+type Instances []Instance
+
+// SnapshotReq defines the fields for an instance snapshot
+type SnapshotReq struct {
+	Name string `json:"snapshotName"`
+}
+
+// BackupWorkflowReq defines the fields for a scheduled backup workflow. The
+// workflow runs until deleted unless MaxExecutionCount is set
+type BackupWorkflowReq struct {
+	Cron              string `json:"cron"`
+	InstanceID        string `json:"instanceId"`
+	MaxExecutionCount *int   `json:"maxExecutionCount,omitempty"`
+	Name              string `json:"name"`
+	Rotation          int    `json:"rotation"`
+}
+
+// BackupWorkflow is a go representation of a scheduled backup workflow
+type BackupWorkflow struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	BackupName string `json:"backupName"`
+	InstanceID string `json:"instanceId"`
+	Cron       string `json:"cron"`
+	CreatedAt  string `json:"createdAt"`
+}
+
+// ResizeReq defines the fields for an instance resize
+type ResizeReq struct {
+	FlavorID string `json:"flavorId"`
+}
+
+// RescueModeReq defines the fields to enter or leave rescue mode
+type RescueModeReq struct {
+	ImageID string `json:"imageId,omitempty"`
+	Rescue  bool   `json:"rescue"`
+}
+
+// RescueMode is the result of a rescue mode change
+type RescueMode struct {
+	AdminPassword string `json:"adminPassword"`
+}
+
+// ReinstallReq defines the fields for an instance reinstall
+type ReinstallReq struct {
+	ImageID string `json:"imageId"`
 }
 
 // RebootReq defines the fields for a VM reboot
@@ -140,6 +331,31 @@ type RebootReq struct {
 	Type string `json:"type"`
 }
 
+// VolumeReq defines the fields for a block storage volume creation
+type VolumeReq struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	Type   string `json:"type"`
+	Region string `json:"region"`
+}
+
+// Volume is a go representation of a Cloud block storage volume
+type Volume struct {
+	Name         string   `json:"name"`
+	ID           string   `json:"id"`
+	Size         int      `json:"size"`
+	Type         string   `json:"type"`
+	Region       string   `json:"region"`
+	Status       string   `json:"status"`
+	AttachedTo   []string `json:"attachedTo"`
+	CreationDate string   `json:"creationDate"`
+}
+
+// VolumeAttachReq defines the fields for a volume attach or detach
+type VolumeAttachReq struct {
+	InstanceID string `json:"instanceId"`
+}
+
 // NewAPI instanciates a Cloud API driver from credentials, for a given endpoint. See github.com/ovh/go-ovh for more informations
 func NewAPI(endpoint, applicationKey, applicationSecret, consumerKey string) (api *API, err error) {
 	client, err := ovh.NewClient(endpoint, applicationKey, applicationSecret, consumerKey)
@@ -231,7 +447,65 @@ func (a *API) GetPrivateNetworkByName(projectID, networkName string) (network *N
 		networkNames = append(networkNames, network.Name)
 	}
 
-	return nil, fmt.Errorf("Invalid private network %s. List of valid private networks include %s", networkName, strings.Join(networkNames[:], ", "))
+	return nil, &notFoundError{fmt.Sprintf("Invalid private network %s. List of valid private networks include %s", networkName, strings.Join(networkNames[:], ", "))}
+}
+
+// CreatePrivateNetwork creates a new private network in region and returns resulting object
+func (a *API) CreatePrivateNetwork(projectID, name string, vlanID int, region string) (network *Network, err error) {
+	var networkReq NetworkReq
+	networkReq.Name = name
+	networkReq.VlanID = vlanID
+	networkReq.Regions = []string{region}
+
+	url := fmt.Sprintf("/cloud/project/%s/network/private", projectID)
+	err = a.client.Post(url, networkReq, &network)
+	return network, err
+}
+
+// GetPrivateNetwork returns the details of a private network given its id
+func (a *API) GetPrivateNetwork(projectID, networkID string) (network *Network, err error) {
+	url := fmt.Sprintf("/cloud/project/%s/network/private/%s", projectID, networkID)
+	err = a.client.Get(url, &network)
+	return network, err
+}
+
+// DeletePrivateNetwork deletes a private network and its subnets
+func (a *API) DeletePrivateNetwork(projectID, networkID string) (err error) {
+	url := fmt.Sprintf("/cloud/project/%s/network/private/%s", projectID, networkID)
+	err = a.client.Delete(url, nil)
+	if apierror, ok := err.(*ovh.APIError); ok && apierror.Code == 404 {
+		err = nil
+	}
+	return err
+}
+
+// GetSubnets returns the subnets of a private network
+func (a *API) GetSubnets(projectID, networkID string) (subnets Subnets, err error) {
+	url := fmt.Sprintf("/cloud/project/%s/network/private/%s/subnet", projectID, networkID)
+	err = a.client.Get(url, &subnets)
+	return subnets, err
+}
+
+// CreateSubnet creates a DHCP enabled subnet without gateway in a private network
+func (a *API) CreateSubnet(projectID, networkID, region, cidr, dhcpStart, dhcpEnd string) (subnet *Subnet, err error) {
+	var subnetReq SubnetReq
+	subnetReq.DHCP = true
+	subnetReq.Start = dhcpStart
+	subnetReq.End = dhcpEnd
+	subnetReq.Network = cidr
+	subnetReq.NoGateway = true
+	subnetReq.Region = region
+
+	url := fmt.Sprintf("/cloud/project/%s/network/private/%s/subnet", projectID, networkID)
+	err = a.client.Post(url, subnetReq, &subnet)
+	return subnet, err
+}
+
+// GetQuotas returns the usage and limits of a project, per region
+func (a *API) GetQuotas(projectID string) (quotas Quotas, err error) {
+	url := fmt.Sprintf("/cloud/project/%s/quota", projectID)
+	err = a.client.Get(url, &quotas)
+	return quotas, err
 }
 
 // GetRegions returns the list of valid regions for a given project
@@ -241,6 +515,13 @@ func (a *API) GetRegions(projectID string) (regions Regions, err error) {
 	return regions, err
 }
 
+// GetRegion returns the details of a region, including its services
+func (a *API) GetRegion(projectID, region string) (details *Region, err error) {
+	url := fmt.Sprintf("/cloud/project/%s/region/%s", projectID, region)
+	err = a.client.Get(url, &details)
+	return details, err
+}
+
 // GetFlavors returns the list of available flavors for a given project in a giver zone
 func (a *API) GetFlavors(projectID, region string) (flavors Flavors, err error) {
 	url := fmt.Sprintf("/cloud/project/%s/flavor?region=%s", projectID, region)
@@ -278,27 +559,58 @@ func (a *API) GetImages(projectID, region string) (images Images, err error) {
 	return images, err
 }
 
-// GetImageByName returns the details of an image given its name, a project and a region. This is slower than id access
+// GetSnapshots returns the list of private snapshots for a given project in a given region
+func (a *API) GetSnapshots(projectID, region string) (images Images, err error) {
+	url := fmt.Sprintf("/cloud/project/%s/snapshot?region=%s", projectID, region)
+	err = a.client.Get(url, &images)
+	return images, err
+}
+
+// GetImageByName returns the details of an image or a private snapshot given its name, a project and a region. This is slower than id access
 func (a *API) GetImageByName(projectID, region, imageName string) (image *Image, err error) {
-	// Get image list
+	// Get image list, including private snapshots
 	images, err := a.GetImages(projectID, region)
 	if err != nil {
 		return nil, err
 	}
+	snapshots, err := a.GetSnapshots(projectID, region)
+	if err != nil {
+		return nil, err
+	}
+	images = append(images, snapshots...)
 
-	// Find first matching image
+	// Find matching images. Ids are unique
+	var matches Images
+	hasPrivate := false
 	for _, image := range images {
 		if image.OS != "linux" {
 			continue
 		}
 
-		if image.ID == imageName || image.Name == imageName {
+		if image.ID == imageName {
 			return &image, nil
 		}
+		if image.Name == imageName {
+			matches = append(matches, image)
+			hasPrivate = hasPrivate || image.Visibility != "public"
+		}
+	}
+
+	// Public images may be listed more than once, take the first one. A
+	// snapshot name may be ambiguous
+	if len(matches) == 1 || (len(matches) > 1 && !hasPrivate) {
+		return &matches[0], nil
+	}
+	if len(matches) > 1 {
+		var descriptions []string
+		for _, image := range matches {
+			descriptions = append(descriptions, fmt.Sprintf("%s (%s, %s, created %s)", image.ID, image.Visibility, image.Region, image.CreationDate))
+		}
+		return nil, fmt.Errorf("Image name '%s' matches several images: %s. Please use an image id instead", imageName, strings.Join(descriptions, ", "))
 	}
 
 	// Ooops
-	return nil, fmt.Errorf("Image '%s' does not exist on OVH cloud. To find a list of available images, please visit %s", imageName, CustomerInterface)
+	return nil, &notFoundError{fmt.Sprintf("Image '%s' does not exist on OVH cloud. To find a list of available images, please visit %s", imageName, CustomerInterface)}
 }
 
 // GetSshkeys returns a list of sshkeys for a given project in a given region
@@ -349,7 +661,7 @@ func (a *API) DeleteSshkey(projectID, instanceID string) (err error) {
 }
 
 // CreateInstance start a new public cloud instance and returns resulting object
-func (a *API) CreateInstance(projectID, name, pubkeyID, flavorID, ImageID, region string, networkIDs []string, monthlyBilling bool) (instance *Instance, err error) {
+func (a *API) CreateInstance(projectID, name, pubkeyID, flavorID, ImageID, region string, networks NetworkParams, monthlyBilling bool, userData string) (instance *Instance, err error) {
 	var instanceReq InstanceReq
 	instanceReq.Name = name
 	instanceReq.SshkeyID = pubkeyID
@@ -357,11 +669,8 @@ func (a *API) CreateInstance(projectID, name, pubkeyID, flavorID, ImageID, regio
 	instanceReq.ImageID = ImageID
 	instanceReq.Region = region
 	instanceReq.MonthlyBilling = monthlyBilling
-
-	for _, v := range networkIDs {
-		networkParam := NetworkParam{ID: v}
-		instanceReq.NetworkParams = append(instanceReq.NetworkParams, networkParam)
-	}
+	instanceReq.UserData = userData
+	instanceReq.NetworkParams = networks
 
 	url := fmt.Sprintf("/cloud/project/%s/instance", projectID)
 	err = a.client.Post(url, instanceReq, &instance)
@@ -382,6 +691,70 @@ func (a *API) RebootInstance(projectID, instanceID string, hard bool) (err error
 	return err
 }
 
+// CreateSnapshot snapshots an instance into a private image named name
+func (a *API) CreateSnapshot(projectID, instanceID, name string) (err error) {
+	url := fmt.Sprintf("/cloud/project/%s/instance/%s/snapshot", projectID, instanceID)
+	err = a.client.Post(url, SnapshotReq{Name: name}, nil)
+	return err
+}
+
+// ResizeInstance migrates an instance to another flavor
+func (a *API) ResizeInstance(projectID, instanceID, flavorID string) (err error) {
+	url := fmt.Sprintf("/cloud/project/%s/instance/%s/resize", projectID, instanceID)
+	err = a.client.Post(url, ResizeReq{FlavorID: flavorID}, nil)
+	return err
+}
+
+// RescueInstance reboots an instance on a rescue image, or back on its own disk
+func (a *API) RescueInstance(projectID, instanceID, imageID string, rescue bool) (rescueMode *RescueMode, err error) {
+	url := fmt.Sprintf("/cloud/project/%s/instance/%s/rescueMode", projectID, instanceID)
+	err = a.client.Post(url, RescueModeReq{ImageID: imageID, Rescue: rescue}, &rescueMode)
+	return rescueMode, err
+}
+
+// ReinstallInstance reinstalls an instance from an image, keeping its id and addresses
+func (a *API) ReinstallInstance(projectID, instanceID, imageID string) (instance *Instance, err error) {
+	url := fmt.Sprintf("/cloud/project/%s/instance/%s/reinstall", projectID, instanceID)
+	err = a.client.Post(url, ReinstallReq{ImageID: imageID}, &instance)
+	return instance, err
+}
+
+// ActivateMonthlyBilling switches an hourly billed instance to monthly billing
+func (a *API) ActivateMonthlyBilling(projectID, instanceID string) (instance *Instance, err error) {
+	url := fmt.Sprintf("/cloud/project/%s/instance/%s/activeMonthlyBilling", projectID, instanceID)
+	err = a.client.Post(url, nil, &instance)
+	return instance, err
+}
+
+// StartInstance starts a stopped instance
+func (a *API) StartInstance(projectID, instanceID string) (err error) {
+	url := fmt.Sprintf("/cloud/project/%s/instance/%s/start", projectID, instanceID)
+	err = a.client.Post(url, nil, nil)
+	return err
+}
+
+// StopInstance stops a running instance
+func (a *API) StopInstance(projectID, instanceID string) (err error) {
+	url := fmt.Sprintf("/cloud/project/%s/instance/%s/stop", projectID, instanceID)
+	err = a.client.Post(url, nil, nil)
+	return err
+}
+
+// ShelveInstance shelves an instance. Unlike stopped instances, shelved
+// instances release their compute resources and are not billed
+func (a *API) ShelveInstance(projectID, instanceID string) (err error) {
+	url := fmt.Sprintf("/cloud/project/%s/instance/%s/shelve", projectID, instanceID)
+	err = a.client.Post(url, nil, nil)
+	return err
+}
+
+// UnshelveInstance restores a shelved instance
+func (a *API) UnshelveInstance(projectID, instanceID string) (err error) {
+	url := fmt.Sprintf("/cloud/project/%s/instance/%s/unshelve", projectID, instanceID)
+	err = a.client.Post(url, nil, nil)
+	return err
+}
+
 // DeleteInstance stops and destroys a public cloud instance
 func (a *API) DeleteInstance(projectID, instanceID string) (err error) {
 	url := fmt.Sprintf("/cloud/project/%s/instance/%s", projectID, instanceID)
@@ -396,5 +769,128 @@ func (a *API) DeleteInstance(projectID, instanceID string) (err error) {
 func (a *API) GetInstance(projectID, instanceID string) (instance *Instance, err error) {
 	url := fmt.Sprintf("/cloud/project/%s/instance/%s", projectID, instanceID)
 	err = a.client.Get(url, &instance)
-	return instance, nil
+	return instance, err
+}
+
+// GetInstances returns the list of instances of a project in a given region, or in all regions if region is empty
+func (a *API) GetInstances(projectID, region string) (instances Instances, err error) {
+	url := fmt.Sprintf("/cloud/project/%s/instance", projectID)
+	if region != "" {
+		url += "?region=" + region
+	}
+	err = a.client.Get(url, &instances)
+	return instances, err
+}
+
+// GetFailoverIPs returns the failover IPs of a project
+func (a *API) GetFailoverIPs(projectID string) (ips FailoverIPs, err error) {
+	url := fmt.Sprintf("/cloud/project/%s/ip/failover", projectID)
+	err = a.client.Get(url, &ips)
+	return ips, err
+}
+
+// AttachFailoverIP routes a failover IP to an instance
+func (a *API) AttachFailoverIP(projectID, failoverIPID, instanceID string) (ip *FailoverIP, err error) {
+	url := fmt.Sprintf("/cloud/project/%s/ip/failover/%s/attach", projectID, failoverIPID)
+	err = a.client.Post(url, FailoverIPAttachReq{InstanceID: instanceID}, &ip)
+	return ip, err
+}
+
+// notFoundError is returned when a lookup by name does not match any resource
+type notFoundError struct {
+	message string
+}
+
+func (e *notFoundError) Error() string {
+	return e.message
+}
+
+// IsNotFound returns true if err is an OVH API error for a missing resource
+// or a lookup by name that did not match any resource
+func IsNotFound(err error) bool {
+	if _, ok := err.(*notFoundError); ok {
+		return true
+	}
+	apierror, ok := err.(*ovh.APIError)
+	return ok && apierror.Code == 404
+}
+
+// CreateVolume creates a new block storage volume and returns resulting object
+func (a *API) CreateVolume(projectID, region, name, volumeType string, size int) (volume *Volume, err error) {
+	var volumeReq VolumeReq
+	volumeReq.Name = name
+	volumeReq.Size = size
+	volumeReq.Type = volumeType
+	volumeReq.Region = region
+
+	url := fmt.Sprintf("/cloud/project/%s/volume", projectID)
+	err = a.client.Post(url, volumeReq, &volume)
+	return volume, err
+}
+
+// GetVolume returns the details of a block storage volume given its id
+func (a *API) GetVolume(projectID, volumeID string) (volume *Volume, err error) {
+	url := fmt.Sprintf("/cloud/project/%s/volume/%s", projectID, volumeID)
+	err = a.client.Get(url, &volume)
+	return volume, err
+}
+
+// AttachVolume attaches a block storage volume to an instance
+func (a *API) AttachVolume(projectID, volumeID, instanceID string) (err error) {
+	url := fmt.Sprintf("/cloud/project/%s/volume/%s/attach", projectID, volumeID)
+	err = a.client.Post(url, VolumeAttachReq{InstanceID: instanceID}, nil)
+	return err
+}
+
+// DetachVolume detaches a block storage volume from an instance
+func (a *API) DetachVolume(projectID, volumeID, instanceID string) (err error) {
+	url := fmt.Sprintf("/cloud/project/%s/volume/%s/detach", projectID, volumeID)
+	err = a.client.Post(url, VolumeAttachReq{InstanceID: instanceID}, nil)
+	if apierror, ok := err.(*ovh.APIError); ok && apierror.Code == 404 {
+		err = nil
+	}
+	return err
+}
+
+// DeleteVolume destroys a block storage volume
+func (a *API) DeleteVolume(projectID, volumeID string) (err error) {
+	url := fmt.Sprintf("/cloud/project/%s/volume/%s", projectID, volumeID)
+	err = a.client.Delete(url, nil)
+	if apierror, ok := err.(*ovh.APIError); ok && apierror.Code == 404 {
+		err = nil
+	}
+	return err
+}
+
+// DeleteSnapshot deletes a private snapshot
+func (a *API) DeleteSnapshot(projectID, imageID string) (err error) {
+	url := fmt.Sprintf("/cloud/project/%s/snapshot/%s", projectID, imageID)
+	err = a.client.Delete(url, nil)
+	if apierror, ok := err.(*ovh.APIError); ok && apierror.Code == 404 {
+		err = nil
+	}
+	return err
+}
+
+// CreateBackupWorkflow schedules snapshots of an instance, keeping the last rotation ones
+func (a *API) CreateBackupWorkflow(projectID, region, instanceID, name, cron string, rotation int) (workflow *BackupWorkflow, err error) {
+	var workflowReq BackupWorkflowReq
+	workflowReq.Cron = cron
+	workflowReq.InstanceID = instanceID
+	workflowReq.Name = name
+	workflowReq.Rotation = rotation
+
+	url := fmt.Sprintf("/cloud/project/%s/region/%s/workflow/backup", projectID, region)
+	err = a.client.Post(url, workflowReq, &workflow)
+	return workflow, err
+}
+
+// DeleteBackupWorkflow deletes a scheduled backup workflow. Existing backups are kept
+func (a *API) DeleteBackupWorkflow(projectID, region, workflowID string) (err error) {
+	url := fmt.Sprintf("/cloud/project/%s/region/%s/workflow/backup/%s", projectID, region, workflowID)
+	err = a.client.Delete(url, nil)
+	if apierror, ok := err.(*ovh.APIError); ok && apierror.Code == 404 {
+		err = nil
+	}
+	return err
 }
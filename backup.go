@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+// checkBackupSchedule validates the automated backup options
+func (d *Driver) checkBackupSchedule() error {
+	if d.BackupCron == "" {
+		return nil
+	}
+
+	if len(strings.Fields(d.BackupCron)) != 5 {
+		return fmt.Errorf("Invalid backup schedule '%s'. Schedules are cron expressions with 5 fields, for instance '0 3 * * *'", d.BackupCron)
+	}
+	if d.BackupRotation < 1 {
+		return fmt.Errorf("Invalid backup rotation %d. At least one backup must be kept", d.BackupRotation)
+	}
+	return nil
+}
+
+// backupNamePattern matches the names of the snapshots made by a backup
+// workflow, the backup name followed by the execution date
+func backupNamePattern(backupName string) *regexp.Regexp {
+	return regexp.MustCompile(`^` + regexp.QuoteMeta(backupName) + `-\d{4}-\d{2}-\d{2}([T ]\d{2}:\d{2}(:\d{2})?Z?)?$`)
+}
+
+// createBackupWorkflow schedules automated snapshots of the instance, if requested
+func (d *Driver) createBackupWorkflow(created *rollback) error {
+	if d.BackupCron == "" {
+		return nil
+	}
+
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	// Backups are named after the workflow, the instance id keeps it unique
+	log.Info("Scheduling backups of OVH instance ", d.InstanceID, "...")
+	workflow, err := client.CreateBackupWorkflow(d.ProjectID, d.RegionName, d.InstanceID, d.MachineName+"-backup-"+d.InstanceID, d.BackupCron, d.BackupRotation)
+	if err != nil {
+		return err
+	}
+	d.BackupWorkflowID = workflow.ID
+	d.BackupName = workflow.BackupName
+	if d.BackupName == "" {
+		d.BackupName = workflow.Name
+	}
+
+	workflowID := workflow.ID
+	created.add("backup workflow "+workflowID, func() error {
+		err := client.DeleteBackupWorkflow(d.ProjectID, d.RegionName, workflowID)
+		if err == nil {
+			d.BackupWorkflowID = ""
+		}
+		return err
+	})
+	return nil
+}
+
+// removeBackups deletes the backup workflow and, if requested, the backups it made
+func (d *Driver) removeBackups() error {
+	if d.BackupWorkflowID == "" {
+		return nil
+	}
+
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	log.Debug("deleting backup workflow...", map[string]interface{}{"WorkflowID": d.BackupWorkflowID})
+	err = client.DeleteBackupWorkflow(d.ProjectID, d.RegionName, d.BackupWorkflowID)
+	if err != nil {
+		return err
+	}
+	d.BackupWorkflowID = ""
+
+	if !d.BackupPurge {
+		log.Info("Backups of ", d.MachineName, " are kept in the project")
+		return nil
+	}
+
+	if d.BackupName == "" {
+		return nil
+	}
+
+	// Backups are snapshots named after the workflow. Other snapshots, and
+	// backups of other machines whose name starts the same, are kept
+	snapshots, err := client.GetSnapshots(d.ProjectID, d.RegionName)
+	if err != nil {
+		return err
+	}
+	pattern := backupNamePattern(d.BackupName)
+	for _, snapshot := range snapshots {
+		if !pattern.MatchString(snapshot.Name) {
+			continue
+		}
+		log.Debug("deleting backup...", map[string]interface{}{"ImageID": snapshot.ID, "Name": snapshot.Name})
+		err = client.DeleteSnapshot(d.ProjectID, snapshot.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
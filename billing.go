@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+// getAutoMonthlyAfter parses the age after which the machine switches to
+// monthly billing. Zero means never
+func (d *Driver) getAutoMonthlyAfter() (time.Duration, error) {
+	if d.AutoMonthlyAfter == "" {
+		return 0, nil
+	}
+
+	after, err := time.ParseDuration(d.AutoMonthlyAfter)
+	if err != nil || after <= 0 {
+		return 0, fmt.Errorf("Invalid monthly billing delay '%s'. Please use a duration such as 360h", d.AutoMonthlyAfter)
+	}
+	return after, nil
+}
+
+// ActivateMonthlyBilling switches the machine to monthly billing
+func (d *Driver) ActivateMonthlyBilling() error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	instance, err := client.GetInstance(d.ProjectID, d.InstanceID)
+	if err != nil {
+		return err
+	}
+
+	if instance.MonthlyBilling == nil {
+		log.Info("Switching OVH instance ", d.InstanceID, " to monthly billing...")
+		_, err = client.ActivateMonthlyBilling(d.ProjectID, d.InstanceID)
+		if err != nil {
+			return err
+		}
+	}
+
+	d.BillingPeriod = "monthly"
+	return nil
+}
+
+// autoMonthlyBilling switches the machine to monthly billing if it is hourly
+// billed and older than its monthly billing delay. It reports whether it did
+func (d *Driver) autoMonthlyBilling() (switched bool, err error) {
+	after, err := d.getAutoMonthlyAfter()
+	if err != nil || after == 0 || d.BillingPeriod == "monthly" {
+		return false, err
+	}
+
+	client, err := d.getClient()
+	if err != nil {
+		return false, err
+	}
+
+	instance, err := client.GetInstance(d.ProjectID, d.InstanceID)
+	if err != nil {
+		return false, err
+	}
+
+	created, err := time.Parse(time.RFC3339, instance.Created)
+	if err != nil {
+		return false, fmt.Errorf("Invalid creation date '%s' for instance %s", instance.Created, d.InstanceID)
+	}
+	log.Debug("Machine", map[string]interface{}{
+		"Name":    d.MachineName,
+		"Created": instance.Created,
+		"After":   after.String(),
+	})
+
+	if time.Since(created) < after {
+		return false, nil
+	}
+	return true, d.ActivateMonthlyBilling()
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/docker/machine/libmachine/drivers"
+)
+
+// command is a maintenance operation run directly from the driver binary,
+// outside of docker-machine: docker-machine-driver-ovh <command> [args]
+type command struct {
+	Usage       string
+	Description string
+	MinArgs     int
+	Run         func(args []string) error
+}
+
+// commands lists the available maintenance operations by name
+var commands = map[string]command{
+	"move-ip": {
+		Usage:       "move-ip OLD-MACHINE NEW-MACHINE",
+		Description: "Move the floating IP of OLD-MACHINE to NEW-MACHINE",
+		MinArgs:     2,
+		Run: func(args []string) error {
+			from, err := loadMachine(args[0])
+			if err != nil {
+				return err
+			}
+			to, err := loadMachine(args[1])
+			if err != nil {
+				return err
+			}
+
+			err = MoveFloatingIP(from, to)
+			if err != nil {
+				return err
+			}
+
+			err = saveMachine(to)
+			if err != nil {
+				return err
+			}
+			return saveMachine(from)
+		},
+	},
+	"snapshot": {
+		Usage:       "snapshot MACHINE SNAPSHOT-NAME",
+		Description: "Create a private image of MACHINE, usable with --ovh-image",
+		MinArgs:     2,
+		Run: func(args []string) error {
+			d, err := loadMachine(args[0])
+			if err != nil {
+				return err
+			}
+
+			image, err := d.Snapshot(args[1])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Snapshot %s is ready with id %s\n", image.Name, image.ID)
+			return nil
+		},
+	},
+	"rescue": {
+		Usage:       "rescue MACHINE [IMAGE]",
+		Description: "Boot MACHINE on a rescue system, its disk is left untouched",
+		MinArgs:     1,
+		Run: func(args []string) error {
+			d, err := loadMachine(args[0])
+			if err != nil {
+				return err
+			}
+
+			image := ""
+			if len(args) > 1 {
+				image = args[1]
+			}
+			err = d.EnterRescue(image)
+			if err != nil {
+				return err
+			}
+			if d.RescuePassword != "" {
+				fmt.Printf("Rescue system of %s is ready. User: %s, password: %s\n", d.MachineName, RescueSSHUserName, d.RescuePassword)
+			} else {
+				fmt.Printf("Rescue system of %s is ready. User: %s\n", d.MachineName, RescueSSHUserName)
+			}
+			return saveMachine(d)
+		},
+	},
+	"rescue-exit": {
+		Usage:       "rescue-exit MACHINE",
+		Description: "Boot MACHINE back on its own disk",
+		MinArgs:     1,
+		Run: func(args []string) error {
+			d, err := loadMachine(args[0])
+			if err != nil {
+				return err
+			}
+
+			err = d.ExitRescue()
+			if err != nil {
+				return err
+			}
+			return saveMachine(d)
+		},
+	},
+	"monthly-billing": {
+		Usage:       "monthly-billing MACHINE",
+		Description: "Switch MACHINE to monthly billing",
+		MinArgs:     1,
+		Run: func(args []string) error {
+			d, err := loadMachine(args[0])
+			if err != nil {
+				return err
+			}
+
+			err = d.ActivateMonthlyBilling()
+			if err != nil {
+				return err
+			}
+			return saveMachine(d)
+		},
+	},
+	"monthly-billing-scan": {
+		Usage:       "monthly-billing-scan",
+		Description: "Switch machines older than their --ovh-auto-monthly-after to monthly billing",
+		Run: func(args []string) error {
+			names, err := listMachines()
+			if err != nil {
+				return err
+			}
+
+			failed := 0
+			for _, name := range names {
+				d, err := loadMachine(name)
+				if IsNotFound(err) {
+					continue
+				}
+				switched := false
+				if err == nil {
+					switched, err = d.autoMonthlyBilling()
+				}
+				if err == nil && switched {
+					err = saveMachine(d)
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Machine %s: %s\n", name, err)
+					failed++
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d machines could not be checked", failed)
+			}
+			return nil
+		},
+	},
+	"reinstall": {
+		Usage:       "reinstall MACHINE IMAGE",
+		Description: "Reinstall MACHINE from IMAGE, keeping its instance and addresses",
+		MinArgs:     2,
+		Run: func(args []string) error {
+			d, err := loadMachine(args[0])
+			if err != nil {
+				return err
+			}
+
+			err = d.Reinstall(args[1])
+			if err != nil {
+				return err
+			}
+			err = saveMachine(d)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Machine %s was reinstalled. Run 'docker-machine provision %s' to install Docker again\n", d.MachineName, d.MachineName)
+			return nil
+		},
+	},
+	"resize": {
+		Usage:       "resize MACHINE FLAVOR",
+		Description: "Move MACHINE to a bigger FLAVOR, keeping its disk",
+		MinArgs:     2,
+		Run: func(args []string) error {
+			d, err := loadMachine(args[0])
+			if err != nil {
+				return err
+			}
+
+			err = d.Resize(args[1])
+			if err != nil {
+				return err
+			}
+			return saveMachine(d)
+		},
+	},
+}
+
+// runCommand runs a maintenance command and returns the process exit code
+func runCommand(name string, args []string) int {
+	cmd, ok := commands[name]
+	if !ok {
+		var names []string
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Fprintf(os.Stderr, "Unknown command '%s'. Available commands:\n", name)
+		for _, name := range names {
+			fmt.Fprintf(os.Stderr, "  %-40s %s\n", commands[name].Usage, commands[name].Description)
+		}
+		return 2
+	}
+
+	if len(args) < cmd.MinArgs {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s\n", filepath.Base(os.Args[0]), cmd.Usage)
+		return 2
+	}
+
+	if err := cmd.Run(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// getMachinesPath returns the directory of the docker-machine machines store
+func getMachinesPath() string {
+	storePath := os.Getenv("MACHINE_STORAGE_PATH")
+	if storePath == "" {
+		storePath = filepath.Join(os.Getenv("HOME"), ".docker", "machine")
+	}
+	return filepath.Join(storePath, "machines")
+}
+
+// getMachineConfigPath returns the path of the docker-machine configuration of a machine
+func getMachineConfigPath(name string) string {
+	return filepath.Join(getMachinesPath(), name, "config.json")
+}
+
+// listMachines returns the names of all machines in the docker-machine store
+func listMachines() (names []string, err error) {
+	entries, err := ioutil.ReadDir(getMachinesPath())
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// loadMachine loads the driver state of an OVH machine from the docker-machine store
+func loadMachine(name string) (*Driver, error) {
+	raw, err := ioutil.ReadFile(getMachineConfigPath(name))
+	if err != nil {
+		return nil, fmt.Errorf("Could not load machine %s: %s", name, err)
+	}
+
+	var config struct {
+		DriverName string
+		Driver     json.RawMessage
+	}
+	if err = json.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("Could not load machine %s: %s", name, err)
+	}
+	if config.DriverName != "ovh" {
+		return nil, &notFoundError{fmt.Sprintf("Machine %s does not use the ovh driver", name)}
+	}
+
+	d := &Driver{BaseDriver: &drivers.BaseDriver{}}
+	if err = json.Unmarshal(config.Driver, d); err != nil {
+		return nil, fmt.Errorf("Could not load machine %s: %s", name, err)
+	}
+	return d, nil
+}
+
+// saveMachine stores the driver state of an OVH machine in the docker-machine
+// store, leaving the rest of its configuration untouched
+func saveMachine(d *Driver) error {
+	path := getMachineConfigPath(d.MachineName)
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var config map[string]json.RawMessage
+	if err = json.Unmarshal(raw, &config); err != nil {
+		return err
+	}
+	if config["Driver"], err = json.Marshal(d); err != nil {
+		return err
+	}
+
+	raw, err = json.MarshalIndent(config, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, raw, 0600)
+}
@@ -21,19 +21,83 @@ const (
 	statusTimeout = 200
 )
 
+// statusPollInterval is the delay between two status checks while waiting
+var statusPollInterval = 4 * time.Second
+
+// instanceStates maps OVH instance statuses to docker-machine states
+var instanceStates = map[string]state.State{
+	"ACTIVE":            state.Running,
+	"BUILD":             state.Starting,
+	"BUILDING":          state.Starting,
+	"REBOOT":            state.Starting,
+	"REBUILD":           state.Starting,
+	"HARD_REBOOT":       state.Starting,
+	"RESIZE":            state.Starting,
+	"VERIFY_RESIZE":     state.Running,
+	"MIGRATING":         state.Running,
+	"SNAPSHOTTING":      state.Running,
+	"PAUSED":            state.Paused,
+	"SUSPENDED":         state.Saved,
+	"SHUTOFF":           state.Stopped,
+	"SHELVED":           state.Stopped,
+	"SHELVED_OFFLOADED": state.Stopped,
+	"RESCUE":            state.Running,
+	"DELETING":          state.Stopping,
+	"UNKNOWN":           state.Error,
+	"ERROR":             state.Error,
+}
+
+// translateInstanceStatus converts an OVH instance status to a docker-machine
+// state. A deleted instance is reported as not found
+func translateInstanceStatus(status string) (state.State, error) {
+	if status == "DELETED" {
+		return state.None, &notFoundError{"OVH instance is deleted"}
+	}
+
+	st, ok := instanceStates[status]
+	if !ok {
+		return state.None, fmt.Errorf("Unknown OVH instance status '%s'", status)
+	}
+	return st, nil
+}
+
 // Driver is a machine driver for OVH.
 type Driver struct {
 	*drivers.BaseDriver
 
 	// Command line parameters
-	ProjectName        string
-	FlavorName         string
-	RegionName         string
-	PrivateNetworkName string
+	ProjectName         string
+	FlavorName          string
+	RegionName          string
+	PrivateNetworkNames []string
 
 	// Ovh specific parameters
-	BillingPeriod string
-	Endpoint      string
+	BillingPeriod    string
+	AutoMonthlyAfter string
+	StopMode         string
+	Endpoint         string
+	KeepOnFailure    bool
+
+	// Firewall
+	AllowedCIDRs []string
+
+	// Cloud-init user data
+	UserDataFile   string
+	UserDataInline string
+
+	// Block storage
+	VolumeSize int
+	VolumeType string
+	Volumes    []string
+	VolumeKeep bool
+	VolumeIDs  []string
+
+	// Scheduled backups
+	BackupCron       string
+	BackupRotation   int
+	BackupPurge      bool
+	BackupWorkflowID string
+	BackupName       string
 
 	// Internal ids
 	ProjectID   string
@@ -44,13 +108,46 @@ type Driver struct {
 	KeyPairID   string
 	NetworkIDs  []string
 
+	// Private networks, in NIC order
+	PrivateNetworks []PrivateNetwork
+	PublicNetworkID string
+	NoPublicNetwork bool
+	PrivateIP       string
+
+	// Private network creation
+	PrivateNetworkCreate    bool
+	PrivateNetworkVlanID    int
+	PrivateNetworkCIDR      string
+	PrivateNetworkDHCPStart string
+	PrivateNetworkDHCPEnd   string
+
+	// Floating IP, FloatingIPAuto is true when the driver selected it
+	FloatingIPName string
+	FloatingIP     string
+	FloatingIPID   string
+	FloatingIPAuto bool
+
+	// Addresses of the instance and which one docker-machine uses
+	InstanceIPs  IPs
+	IPPreference string
+
+	// IPv6
+	IPv6           bool
+	IPv6Address    string
+	IPv6Docker     bool
+	IPv6DockerCIDR string
+
+	// Rescue mode
+	Rescue         bool
+	RescuePassword string
+
 	// Overloaded credentials
 	ApplicationKey    string
 	ApplicationSecret string
 	ConsumerKey       string
 
 	// internal
-	client *API
+	client CloudAPI
 }
 
 // GetCreateFlags registers the "machine create" flags recognized by this driver, including
@@ -100,9 +197,65 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage: "OVH Cloud Image name or id. Default: Ubuntu 16.04",
 			Value: DefaultImageName,
 		},
-		mcnflag.StringFlag{
+		mcnflag.StringSliceFlag{
 			Name:  "ovh-private-network",
-			Usage: "OVH Cloud (private) network name or vlan number. Default: public network",
+			Usage: "OVH Cloud (private) network name or vlan number. May be repeated. Default: public network",
+			Value: []string{},
+		},
+		mcnflag.BoolFlag{
+			Name:  "ovh-private-network-create",
+			Usage: "Create the OVH Cloud private network if it does not exist",
+		},
+		mcnflag.IntFlag{
+			Name:  "ovh-private-network-vlan",
+			Usage: "OVH Cloud vlan number of the created private network. Default: network name if it is a number",
+			Value: 0,
+		},
+		mcnflag.StringFlag{
+			Name:  "ovh-private-network-cidr",
+			Usage: "OVH Cloud subnet of the created private network. Default: 192.168.0.0/24",
+			Value: DefaultNetworkCIDR,
+		},
+		mcnflag.StringFlag{
+			Name:  "ovh-private-network-dhcp-start",
+			Usage: "First DHCP address of the created private network. Default: second usable address of the subnet",
+			Value: "",
+		},
+		mcnflag.StringFlag{
+			Name:  "ovh-private-network-dhcp-end",
+			Usage: "Last DHCP address of the created private network. Default: last usable address of the subnet",
+			Value: "",
+		},
+		mcnflag.StringFlag{
+			Name:  "ovh-private-ip",
+			Usage: "OVH Cloud static address of the machine on the first private network. Default: DHCP",
+			Value: "",
+		},
+		mcnflag.BoolFlag{
+			Name:  "ovh-no-public-network",
+			Usage: "Do not attach the machine to the public network. Requires a private network",
+		},
+		mcnflag.StringFlag{
+			Name:  "ovh-floating-ip",
+			Usage: "OVH Cloud failover IP to attach to the machine, or 'auto' to use an unused one. Default: none",
+			Value: "",
+		},
+		mcnflag.BoolFlag{
+			Name:  "ovh-ipv6",
+			Usage: "Require an IPv6 address for the machine",
+		},
+		mcnflag.BoolFlag{
+			Name:  "ovh-ipv6-docker",
+			Usage: "Enable IPv6 in Docker, with a subnet of the machine IPv6 block as fixed-cidr-v6",
+		},
+		mcnflag.StringFlag{
+			Name:  "ovh-ipv6-docker-cidr",
+			Usage: "IPv6 subnet routed to the machine, used as Docker fixed-cidr-v6 to enable IPv6 in Docker. Default: a subnet of the machine IPv6 block",
+			Value: "",
+		},
+		mcnflag.StringFlag{
+			Name:  "ovh-ip-preference",
+			Usage: "OVH Cloud address to use for SSH and Docker (public-v4, public-v6 or private). Default: public-v4, private without public network",
 			Value: "",
 		},
 		mcnflag.StringFlag{
@@ -120,6 +273,68 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage: "OVH Cloud billing period (hourly or monthly). Default: hourly",
 			Value: DefaultBillingPeriod,
 		},
+		mcnflag.StringFlag{
+			Name:  "ovh-auto-monthly-after",
+			Usage: "Switch hourly machines to monthly billing once they are older than this duration, for instance 360h. Applied by the 'monthly-billing-scan' command. Default: never",
+			Value: "",
+		},
+		mcnflag.StringFlag{
+			Name:  "ovh-stop-mode",
+			Usage: "OVH Cloud stop mode (stop or shelve). Shelved machines are not billed. Default: stop",
+			Value: DefaultStopMode,
+		},
+		mcnflag.BoolFlag{
+			Name:  "ovh-keep-on-failure",
+			Usage: "Keep OVH Cloud resources when machine creation fails, for debugging",
+		},
+		mcnflag.StringSliceFlag{
+			Name:  "ovh-allowed-cidr",
+			Usage: "Network allowed to reach SSH, Docker and Swarm ports. May be repeated. Default: no firewall",
+			Value: []string{},
+		},
+		mcnflag.StringFlag{
+			Name:  "ovh-userdata",
+			Usage: "OVH Cloud cloud-init user data file to pass to the machine",
+			Value: "",
+		},
+		mcnflag.StringFlag{
+			Name:  "ovh-userdata-inline",
+			Usage: "OVH Cloud cloud-init user data to pass to the machine",
+			Value: "",
+		},
+		mcnflag.IntFlag{
+			Name:  "ovh-volume-size",
+			Usage: "OVH Cloud block storage volume size in GB to attach to the machine. Default: no volume",
+			Value: 0,
+		},
+		mcnflag.StringFlag{
+			Name:  "ovh-volume-type",
+			Usage: "OVH Cloud block storage volume type (classic or high-speed). Default: classic",
+			Value: DefaultVolumeType,
+		},
+		mcnflag.StringSliceFlag{
+			Name:  "ovh-volume",
+			Usage: "OVH Cloud additional block storage volume as SIZE[:TYPE]. May be repeated",
+			Value: []string{},
+		},
+		mcnflag.BoolFlag{
+			Name:  "ovh-volume-keep",
+			Usage: "Keep OVH Cloud block storage volumes when the machine is removed",
+		},
+		mcnflag.StringFlag{
+			Name:  "ovh-backup-cron",
+			Usage: "OVH Cloud automated backup schedule, as a cron expression. Default: no backup",
+			Value: "",
+		},
+		mcnflag.IntFlag{
+			Name:  "ovh-backup-rotation",
+			Usage: "Number of OVH Cloud automated backups to keep",
+			Value: DefaultBackupRotation,
+		},
+		mcnflag.BoolFlag{
+			Name:  "ovh-backup-purge",
+			Usage: "Delete OVH Cloud automated backups when the machine is removed",
+		},
 	}
 }
 
@@ -129,7 +344,7 @@ func (d *Driver) DriverName() string {
 }
 
 // getClient returns an OVH API client
-func (d *Driver) getClient() (api *API, err error) {
+func (d *Driver) getClient() (api CloudAPI, err error) {
 	if d.client == nil {
 		client, err := NewAPI(d.Endpoint, d.ApplicationKey, d.ApplicationSecret, d.ConsumerKey)
 		if err != nil {
@@ -153,9 +368,34 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.RegionName = flags.String("ovh-region")
 	d.FlavorName = flags.String("ovh-flavor")
 	d.ImageID = flags.String("ovh-image")
-	d.PrivateNetworkName = flags.String("ovh-private-network")
+	d.PrivateNetworkNames = flags.StringSlice("ovh-private-network")
+	d.PrivateNetworkCreate = flags.Bool("ovh-private-network-create")
+	d.PrivateNetworkVlanID = flags.Int("ovh-private-network-vlan")
+	d.PrivateNetworkCIDR = flags.String("ovh-private-network-cidr")
+	d.PrivateNetworkDHCPStart = flags.String("ovh-private-network-dhcp-start")
+	d.PrivateNetworkDHCPEnd = flags.String("ovh-private-network-dhcp-end")
+	d.NoPublicNetwork = flags.Bool("ovh-no-public-network")
+	d.PrivateIP = flags.String("ovh-private-ip")
+	d.IPPreference = flags.String("ovh-ip-preference")
+	d.FloatingIPName = flags.String("ovh-floating-ip")
+	d.IPv6 = flags.Bool("ovh-ipv6")
+	d.IPv6Docker = flags.Bool("ovh-ipv6-docker")
+	d.IPv6DockerCIDR = flags.String("ovh-ipv6-docker-cidr")
 	d.KeyPairName = flags.String("ovh-ssh-key")
 	d.BillingPeriod = flags.String("ovh-billing-period")
+	d.AutoMonthlyAfter = flags.String("ovh-auto-monthly-after")
+	d.StopMode = flags.String("ovh-stop-mode")
+	d.KeepOnFailure = flags.Bool("ovh-keep-on-failure")
+	d.AllowedCIDRs = flags.StringSlice("ovh-allowed-cidr")
+	d.UserDataFile = flags.String("ovh-userdata")
+	d.UserDataInline = flags.String("ovh-userdata-inline")
+	d.VolumeSize = flags.Int("ovh-volume-size")
+	d.VolumeType = flags.String("ovh-volume-type")
+	d.Volumes = flags.StringSlice("ovh-volume")
+	d.VolumeKeep = flags.Bool("ovh-volume-keep")
+	d.BackupCron = flags.String("ovh-backup-cron")
+	d.BackupRotation = flags.Int("ovh-backup-rotation")
+	d.BackupPurge = flags.Bool("ovh-backup-purge")
 
 	// Swarm configuration, must be in each driver
 	d.SwarmMaster = flags.Bool("swarm-master")
@@ -180,6 +420,34 @@ func (d *Driver) PreCreateCheck() error {
 		return fmt.Errorf("Invalid billing period '%s'. Please select one of 'hourly', 'monthly'", d.BillingPeriod)
 	}
 	log.Debug("Selecting billing period", d.BillingPeriod)
+	if _, err := d.getAutoMonthlyAfter(); err != nil {
+		return err
+	}
+
+	// Validate stop mode
+	log.Debug("Validating stop mode")
+	if d.StopMode != "stop" && d.StopMode != "shelve" {
+		return fmt.Errorf("Invalid stop mode '%s'. Please select one of 'stop', 'shelve'", d.StopMode)
+	}
+	log.Debug("Selecting stop mode", d.StopMode)
+
+	// Validate user data
+	log.Debug("Validating user data")
+	if _, err := d.getUserData(); err != nil {
+		return err
+	}
+
+	// Validate volumes
+	log.Debug("Validating volumes")
+	if _, err := d.getVolumeSpecs(); err != nil {
+		return err
+	}
+
+	// Validate backup schedule
+	log.Debug("Validating backup schedule")
+	if err := d.checkBackupSchedule(); err != nil {
+		return err
+	}
 
 	// Validate project id
 	log.Debug("Validating project")
@@ -243,6 +511,12 @@ func (d *Driver) PreCreateCheck() error {
 	d.FlavorID = flavor.ID
 	log.Debug("Found flavor id ", d.FlavorID)
 
+	// Validate quotas, before anything is created
+	log.Debug("Validating quotas")
+	if err := d.checkQuotas(flavor); err != nil {
+		return err
+	}
+
 	// Validate image
 	log.Debug("Validating image")
 	image, err := client.GetImageByName(d.ProjectID, d.RegionName, d.ImageID)
@@ -252,26 +526,101 @@ func (d *Driver) PreCreateCheck() error {
 	d.ImageID = image.ID
 	log.Debug("Found image id ", d.ImageID)
 
-	// Validate private network
-	log.Debug("Validating private network")
-	if d.PrivateNetworkName != "" {
-		privateNetwork, err := client.GetPrivateNetworkByName(d.ProjectID, d.PrivateNetworkName)
+	// Validate private networks, keeping the NIC order
+	log.Debug("Validating private networks")
+	if d.NoPublicNetwork && len(d.PrivateNetworkNames) == 0 {
+		return fmt.Errorf("Machines without public network need at least one private network. Please use the '--ovh-private-network' option")
+	}
+	d.PrivateNetworks = nil
+	d.PublicNetworkID = ""
+	for _, networkName := range d.PrivateNetworkNames {
+		privateNetwork, err := client.GetPrivateNetworkByName(d.ProjectID, networkName)
+		if IsNotFound(err) && d.PrivateNetworkCreate {
+			network, err := d.newPrivateNetwork(networkName)
+			if err != nil {
+				return err
+			}
+			d.PrivateNetworks = append(d.PrivateNetworks, network)
+			log.Info("Private network ", networkName, " does not exist and will be created")
+			continue
+		}
 		if err != nil {
 			return err
 		}
-		d.NetworkIDs = append(d.NetworkIDs, privateNetwork.ID)
+		d.PrivateNetworks = append(d.PrivateNetworks, PrivateNetwork{
+			Name:   privateNetwork.Name,
+			ID:     privateNetwork.ID,
+			VlanID: privateNetwork.VlanID,
+		})
 		log.Debug("Found private network id ", privateNetwork.ID)
+	}
+
+	// Validate static private IP
+	if d.PrivateIP != "" {
+		log.Debug("Validating private IP")
+		err = d.checkPrivateIP()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Validate floating IP
+	if d.FloatingIPName != "" {
+		log.Debug("Validating floating IP")
+		if d.NoPublicNetwork {
+			return fmt.Errorf("Floating IPs need a public network. Please remove '--ovh-no-public-network'")
+		}
+		floatingIP, err := d.findFloatingIP(d.FloatingIPName)
+		if err != nil {
+			return err
+		}
+		if floatingIP.RoutedTo != "" {
+			log.Warnf("Failover IP %s is routed to instance %s and will be moved to this machine", floatingIP.IP, floatingIP.RoutedTo)
+		}
+		d.FloatingIP = floatingIP.IP
+		d.FloatingIPID = floatingIP.ID
+		d.FloatingIPAuto = d.FloatingIPName == "auto"
+		log.Debug("Found floating IP ", d.FloatingIP)
+	}
+
+	// Validate IPv6
+	if d.IPv6 || d.IPv6Docker || d.IPv6DockerCIDR != "" || d.getIPPreference() == "public-v6" {
+		log.Debug("Validating IPv6")
+		err = d.checkIPv6()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Validate IP preference
+	log.Debug("Validating IP preference")
+	switch d.getIPPreference() {
+	case "public-v4", "public-v6":
+		if d.NoPublicNetwork {
+			return fmt.Errorf("IP preference '%s' needs a public network. Please select 'private' or remove '--ovh-no-public-network'", d.IPPreference)
+		}
+	case "private":
+		if len(d.PrivateNetworkNames) == 0 {
+			return fmt.Errorf("IP preference 'private' needs a private network. Please use the '--ovh-private-network' option")
+		}
+	default:
+		return fmt.Errorf("Invalid IP preference '%s'. Please select one of 'public-v4', 'public-v6', 'private'", d.IPPreference)
+	}
 
+	if d.NoPublicNetwork {
+		log.Debug("Not using public network")
+	} else if len(d.PrivateNetworks) > 0 {
 		publicNetworkID, err := client.GetPublicNetworkID(d.ProjectID)
 		if err != nil {
 			return err
 		}
-		d.NetworkIDs = append(d.NetworkIDs, publicNetworkID)
+		d.PublicNetworkID = publicNetworkID
 		log.Debug("Found public network id ", publicNetworkID)
 
 	} else {
 		log.Debug("No private network found. Using public network")
 	}
+	d.updateNetworkIDs()
 
 	// Use a common key or create a machine specific one
 	keyPath := filepath.Join(d.StorePath, "sshkeys", d.KeyPairName)
@@ -289,16 +638,18 @@ func (d *Driver) PreCreateCheck() error {
 
 	return nil
 }
-//copied from openstack driver
+
+// copied from openstack driver
 func sanitizeKeyPairName(s *string) {
 	*s = strings.Replace(*s, ".", "_", -1)
 }
 
-// ensureSSHKey makes sure an SSH key for the machine exists with requested name
-func (d *Driver) ensureSSHKey() error {
+// ensureSSHKey makes sure an SSH key for the machine exists with requested name.
+// Returns true if the key was uploaded by this call
+func (d *Driver) ensureSSHKey() (created bool, err error) {
 	client, err := d.getClient()
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	// Attempt to get an existing key
@@ -307,7 +658,7 @@ func (d *Driver) ensureSSHKey() error {
 	if sshKey != nil {
 		d.KeyPairID = sshKey.ID
 		log.Debug("Found key id ", d.KeyPairID)
-		return nil
+		return false, nil
 	}
 
 	// Generate key and parent dir if needed
@@ -316,31 +667,31 @@ func (d *Driver) ensureSSHKey() error {
 	keypath := filepath.Dir(keyfile)
 	err = os.MkdirAll(keypath, 0700)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	err = ssh.GenerateSSHKey(d.GetSSHKeyPath())
 	if err != nil {
-		return err
+		return false, err
 	}
 	publicKey, err := ioutil.ReadFile(d.publicSSHKeyPath())
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	// Upload key
 	sshKey, err = client.CreateSshkey(d.ProjectID, d.KeyPairName, string(publicKey))
 	if err != nil {
-		return err
+		return false, err
 	}
 	d.KeyPairID = sshKey.ID
 
 	log.Debug("Created key id ", d.KeyPairID)
-	return nil
+	return true, nil
 }
 
-// waitForInstanceStatus waits until instance reaches status. Copied from openstack Driver
-func (d *Driver) waitForInstanceStatus(status string) (instance *Instance, err error) {
+// waitForInstanceStatus waits until instance reaches one of statuses. Copied from openstack Driver
+func (d *Driver) waitForInstanceStatus(statuses ...string) (instance *Instance, err error) {
 	return instance, mcnutils.WaitForSpecificOrError(func() (bool, error) {
 		instance, err = d.client.GetInstance(d.ProjectID, d.InstanceID)
 		if err != nil {
@@ -352,20 +703,46 @@ func (d *Driver) waitForInstanceStatus(status string) (instance *Instance, err e
 		})
 
 		if instance.Status == "ERROR" {
-			return true, fmt.Errorf("Instance creation failed. Instance is in ERROR state")
+			return true, fmt.Errorf("Instance %s is in ERROR state", d.InstanceID)
 		}
 
-		if instance.Status == status {
-			return true, nil
+		for _, status := range statuses {
+			if instance.Status == status {
+				return true, nil
+			}
 		}
 
 		return false, nil
-	}, (statusTimeout / 4), 4*time.Second)
+	}, (statusTimeout / 4), statusPollInterval)
 }
 
-// GetSSHHostname returns the hostname for SSH
+// GetSSHHostname returns the hostname for SSH. In rescue mode, this is the
+// public address of the rescue system
 func (d *Driver) GetSSHHostname() (string, error) {
-	return d.IPAddress, nil
+	if ip := d.getRescueIP(); d.Rescue && ip != "" {
+		return ip, nil
+	}
+	return d.GetIP()
+}
+
+// GetSSHUsername returns the username for SSH
+func (d *Driver) GetSSHUsername() string {
+	if d.Rescue {
+		return RescueSSHUserName
+	}
+	return d.BaseDriver.GetSSHUsername()
+}
+
+// GetIP returns the address of the machine, following the IP preference
+func (d *Driver) GetIP() (string, error) {
+	ip := d.getPreferredIP()
+	if ip == "" {
+		ip = d.IPAddress
+	}
+	if ip == "" {
+		return "", fmt.Errorf("IP address is not set")
+	}
+	return ip, nil
 }
 
 // GetSSHKeyPath returns the ssh key path
@@ -374,14 +751,51 @@ func (d *Driver) GetSSHKeyPath() string {
 }
 
 // Create a new docker machine instance on OVH Cloud
-func (d *Driver) Create() error {
+func (d *Driver) Create() (err error) {
 	client, err := d.getClient()
 	if err != nil {
 		return err
 	}
 
+	// Tear down partially created resources on failure
+	var created rollback
+	defer func() {
+		if err == nil || len(created.steps) == 0 {
+			return
+		}
+		if d.KeepOnFailure {
+			log.Warn("Machine creation failed. Keeping OVH resources as requested by --ovh-keep-on-failure")
+			return
+		}
+		log.Info("Machine creation failed. Cleaning up OVH resources...")
+		created.run()
+	}()
+
 	// Ensure ssh key
-	err = d.ensureSSHKey()
+	keyCreated, err := d.ensureSSHKey()
+	if err != nil {
+		return err
+	}
+	if keyCreated {
+		keyPairID := d.KeyPairID
+		created.add("SSH key "+d.KeyPairName, func() error {
+			err := client.DeleteSshkey(d.ProjectID, keyPairID)
+			if err == nil {
+				d.KeyPairID = ""
+			}
+			return err
+		})
+	}
+
+	// Create missing private networks
+	err = d.createPrivateNetworks(&created)
+	if err != nil {
+		return err
+	}
+	d.updateNetworkIDs()
+
+	// Build user data
+	userData, err := d.getUserData()
 	if err != nil {
 		return err
 	}
@@ -396,13 +810,22 @@ func (d *Driver) Create() error {
 		d.FlavorID,
 		d.ImageID,
 		d.RegionName,
-		d.NetworkIDs,
+		d.getNetworkParams(),
 		monthlyBilling,
+		userData,
 	)
 	if err != nil {
 		return err
 	}
 	d.InstanceID = instance.ID
+	instanceID := instance.ID
+	created.add("instance "+instanceID, func() error {
+		err := client.DeleteInstance(d.ProjectID, instanceID)
+		if err == nil {
+			d.InstanceID = ""
+		}
+		return err
+	})
 
 	// Wait until instance is ACTIVE
 	log.Debugf("Waiting for OVH instance...", map[string]interface{}{"MachineID": d.InstanceID})
@@ -412,16 +835,64 @@ func (d *Driver) Create() error {
 	}
 
 	// Save Ip address
-	d.IPAddress = ""
+	d.updatePrivateIPAddresses(instance)
+	err = d.updateIPAddress(instance)
+	if err != nil {
+		return err
+	}
+
+	// Attach floating IP
+	if d.FloatingIPID != "" {
+		err = d.attachFloatingIP()
+		if err != nil {
+			return err
+		}
+		d.IPAddress = d.getPreferredIP()
+	}
+
+	// Enable IPv6 in Docker
+	if d.IPv6Docker {
+		err = d.enableDockerIPv6()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Create and attach block storage volumes
+	err = d.createVolumes(&created)
+	if err != nil {
+		return err
+	}
+
+	// Schedule automated backups
+	err = d.createBackupWorkflow(&created)
+	if err != nil {
+		return err
+	}
+
+	// All done !
+	return nil
+}
+
+// updateIPAddress saves the addresses of instance in the driver state and
+// selects the one matching the IP preference
+func (d *Driver) updateIPAddress(instance *Instance) error {
+	d.InstanceIPs = instance.IPAddresses
+	d.IPAddress = d.getPreferredIP()
+
+	d.IPv6Address = ""
 	for _, ip := range instance.IPAddresses {
-		if ip.Type == "public" {
-			d.IPAddress = ip.IP
+		if ip.Type == "public" && getIPVersion(ip) == 6 {
+			d.IPv6Address = ip.IP
 			break
 		}
 	}
+	if d.IPv6 && d.IPv6Address == "" {
+		return fmt.Errorf("No IPv6 found for instance %s. IPv6 may not be available in region %s", instance.ID, d.RegionName)
+	}
 
 	if d.IPAddress == "" {
-		return fmt.Errorf("No IP found for instance %s", instance.ID)
+		return fmt.Errorf("No %s IP found for instance %s", d.getIPPreference(), instance.ID)
 	}
 
 	log.Debugf("IP address found", map[string]interface{}{
@@ -429,7 +900,6 @@ func (d *Driver) Create() error {
 		"IP":        d.IPAddress,
 	})
 
-	// All done !
 	return nil
 }
 
@@ -446,40 +916,34 @@ func (d *Driver) GetState() (state.State, error) {
 		return state.None, err
 	}
 
+	st := state.None
 	instance, err := client.GetInstance(d.ProjectID, d.InstanceID)
-	if err != nil {
-		return state.None, err
-	}
+	if err == nil {
+		log.Debugf("OVH instance", map[string]interface{}{
+			"MachineID": d.InstanceID,
+			"State":     instance.Status,
+		})
 
-	log.Debugf("OVH instance", map[string]interface{}{
-		"MachineID": d.InstanceID,
-		"State":     instance.Status,
-	})
+		// The rescue system is reachable, report it as running so that it may be used
+		if instance.Status == "RESCUE" {
+			log.Warnf("Machine %s is in rescue mode. Use 'docker-machine-driver-ovh rescue-exit %s' to boot it normally", d.MachineName, d.MachineName)
+		}
 
-	switch instance.Status {
-	case "ACTIVE":
-		return state.Running, nil
-	case "PAUSED":
-		return state.Paused, nil
-	case "SUSPENDED":
-		return state.Saved, nil
-	case "SHUTOFF":
-		return state.Stopped, nil
-	case "BUILDING":
-		return state.Starting, nil
-	case "ERROR":
-		return state.Error, nil
-	}
-
-	return state.None, nil
+		st, err = translateInstanceStatus(instance.Status)
+	}
+	if IsNotFound(err) {
+		return state.None, fmt.Errorf("Machine %s does not exist on OVH cloud (instance %s)", d.MachineName, d.InstanceID)
+	}
+	return st, err
 }
 
 // GetURL returns docker daemon URL on this machine
 func (d *Driver) GetURL() (string, error) {
-	if d.IPAddress == "" {
+	ip, err := d.GetIP()
+	if err != nil {
 		return "", nil
 	}
-	return fmt.Sprintf("tcp://%s", net.JoinHostPort(d.IPAddress, "2376")), nil
+	return fmt.Sprintf("tcp://%s", net.JoinHostPort(ip, "2376")), nil
 }
 
 // Remove deletes a machine and it's SSH keys from OVH Cloud
@@ -492,6 +956,20 @@ func (d *Driver) Remove() error {
 		return err
 	}
 
+	// Stop automated backups before deleting the instance
+	err = d.removeBackups()
+	if err != nil {
+		return err
+	}
+
+	// Detach volumes before deleting the instance
+	if d.InstanceID != "" {
+		err = d.detachVolumes()
+		if err != nil {
+			return err
+		}
+	}
+
 	// Deletes instance, if we created it
 	if d.InstanceID != "" {
 		err = client.DeleteInstance(d.ProjectID, d.InstanceID)
@@ -500,6 +978,32 @@ func (d *Driver) Remove() error {
 		}
 	}
 
+	// Deletes volumes, unless asked to keep them
+	if d.VolumeKeep {
+		log.Debug("keeping volumes...", map[string]interface{}{"VolumeIDs": d.VolumeIDs})
+	} else {
+		for _, volumeID := range append([]string{}, d.VolumeIDs...) {
+			log.Debug("deleting volume...", map[string]interface{}{"VolumeID": volumeID})
+			err = d.removeVolume(volumeID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Failover IPs cannot be released, they are unrouted with the instance
+	if d.FloatingIPAuto {
+		log.Info("Floating IP ", d.FloatingIP, " is unused again and may be selected by other machines")
+	} else if d.FloatingIP != "" {
+		log.Info("Floating IP ", d.FloatingIP, " is kept in the project")
+	}
+
+	// Deletes private networks, if we created them
+	err = d.removePrivateNetworks()
+	if err != nil {
+		return err
+	}
+
 	// If key name  does not starts with the machine ID, this is a pre-existing key, keep it
 	if !strings.HasPrefix(d.KeyPairName, d.MachineName) {
 		log.Debugf("keeping key pair...", map[string]interface{}{"KeyPairID": d.KeyPairID})
@@ -534,21 +1038,108 @@ func (d *Driver) Restart() error {
 	return nil
 }
 
-//
-// STUBS
-//
-
-// Kill (STUB) kill machine
+// Kill forcefully powers off a machine. If the instance does not stop, fall
+// back on a hard reboot so that a wedged machine is recovered anyway
 func (d *Driver) Kill() (err error) {
-	return fmt.Errorf("Killing machines is not possible on OVH Cloud")
+	log.Debug("Killing OVH instance...", map[string]interface{}{"MachineID": d.InstanceID})
+
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	// Attempt a hard stop first
+	err = client.StopInstance(d.ProjectID, d.InstanceID)
+	if err == nil {
+		_, err = d.waitForInstanceStatus("SHUTOFF")
+	}
+
+	// Fallback on a hard reboot
+	if err != nil {
+		log.Debug("Stopping failed, hard rebooting OVH instance...", map[string]interface{}{
+			"MachineID": d.InstanceID,
+			"Error":     err,
+		})
+		err = client.RebootInstance(d.ProjectID, d.InstanceID, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Check final state
+	st, err := d.GetState()
+	if err != nil {
+		return err
+	}
+	if st == state.Error {
+		return fmt.Errorf("Instance %s is in ERROR state after kill. Please visit %s", d.InstanceID, CustomerInterface)
+	}
+
+	return nil
 }
 
-// Start (STUB) start machine
+// Start starts a stopped machine
 func (d *Driver) Start() (err error) {
-	return fmt.Errorf("Starting machines is not possible on OVH Cloud")
+	log.Debug("Starting OVH instance...", map[string]interface{}{"MachineID": d.InstanceID})
+
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	instance, err := client.GetInstance(d.ProjectID, d.InstanceID)
+	if err != nil {
+		return err
+	}
+
+	// Shelved instances must be unshelved rather than started
+	if instance.Status == "SHELVED" || instance.Status == "SHELVED_OFFLOADED" {
+		err = client.UnshelveInstance(d.ProjectID, d.InstanceID)
+	} else {
+		err = client.StartInstance(d.ProjectID, d.InstanceID)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Wait until instance is ACTIVE
+	instance, err = d.waitForInstanceStatus("ACTIVE")
+	if err != nil {
+		return err
+	}
+
+	// IPs may have changed while the instance was stopped
+	d.updatePrivateIPAddresses(instance)
+	return d.updateIPAddress(instance)
 }
 
-// Stop (STUB) stop machine
+// Stop stops a running machine. When stop mode is 'shelve', the machine is
+// shelved instead so that it is no longer billed
 func (d *Driver) Stop() (err error) {
-	return fmt.Errorf("Stopping machines is not possible on OVH Cloud")
+	log.Debug("Stopping OVH instance...", map[string]interface{}{"MachineID": d.InstanceID})
+
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	if d.StopMode == "shelve" {
+		err = client.ShelveInstance(d.ProjectID, d.InstanceID)
+		if err != nil {
+			return err
+		}
+
+		// Wait until instance is SHELVED
+		_, err = d.waitForInstanceStatus("SHELVED", "SHELVED_OFFLOADED")
+		return err
+	}
+
+	err = client.StopInstance(d.ProjectID, d.InstanceID)
+	if err != nil {
+		return err
+	}
+
+	// Wait until instance is SHUTOFF
+	_, err = d.waitForInstanceStatus("SHUTOFF")
+	return err
 }
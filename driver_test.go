@@ -0,0 +1,1200 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/ovh/go-ovh/ovh"
+)
+
+func TestTranslateInstanceStatus(t *testing.T) {
+	tests := []struct {
+		status   string
+		expected state.State
+		notFound bool
+		err      bool
+	}{
+		{status: "ACTIVE", expected: state.Running},
+		{status: "BUILD", expected: state.Starting},
+		{status: "BUILDING", expected: state.Starting},
+		{status: "REBOOT", expected: state.Starting},
+		{status: "REBUILD", expected: state.Starting},
+		{status: "HARD_REBOOT", expected: state.Starting},
+		{status: "RESIZE", expected: state.Starting},
+		{status: "VERIFY_RESIZE", expected: state.Running},
+		{status: "MIGRATING", expected: state.Running},
+		{status: "SNAPSHOTTING", expected: state.Running},
+		{status: "PAUSED", expected: state.Paused},
+		{status: "SUSPENDED", expected: state.Saved},
+		{status: "SHUTOFF", expected: state.Stopped},
+		{status: "SHELVED", expected: state.Stopped},
+		{status: "SHELVED_OFFLOADED", expected: state.Stopped},
+		{status: "RESCUE", expected: state.Running},
+		{status: "DELETING", expected: state.Stopping},
+		{status: "UNKNOWN", expected: state.Error},
+		{status: "ERROR", expected: state.Error},
+		{status: "DELETED", expected: state.None, err: true, notFound: true},
+		{status: "", expected: state.None, err: true},
+		{status: "active", expected: state.None, err: true},
+		{status: "SOFT_DELETED", expected: state.None, err: true},
+	}
+
+	for _, test := range tests {
+		st, err := translateInstanceStatus(test.status)
+		if st != test.expected {
+			t.Errorf("status %q: expected state %s, got %s", test.status, test.expected, st)
+		}
+		if (err != nil) != test.err {
+			t.Errorf("status %q: unexpected error %v", test.status, err)
+		}
+		if IsNotFound(err) != test.notFound {
+			t.Errorf("status %q: expected not found %t, got error %v", test.status, test.notFound, err)
+		}
+	}
+
+	// Every mapped status is covered above
+	for status := range instanceStates {
+		found := false
+		for _, test := range tests {
+			found = found || test.status == status
+		}
+		if !found {
+			t.Errorf("status %q is not tested", status)
+		}
+	}
+}
+
+// testFlags implements drivers.DriverOptions from the driver flag defaults
+type testFlags map[string]interface{}
+
+func (f testFlags) String(key string) string {
+	value, _ := f[key].(string)
+	return value
+}
+
+func (f testFlags) StringSlice(key string) []string {
+	value, _ := f[key].([]string)
+	return value
+}
+
+func (f testFlags) Int(key string) int {
+	value, _ := f[key].(int)
+	return value
+}
+
+func (f testFlags) Bool(key string) bool {
+	value, _ := f[key].(bool)
+	return value
+}
+
+// newTestDriver returns a driver configured from its flag defaults and
+// overrides, using cloud as backend
+func newTestDriver(t *testing.T, cloud *fakeCloud, overrides testFlags) *Driver {
+	d := &Driver{BaseDriver: &drivers.BaseDriver{
+		MachineName: "test-machine",
+		StorePath:   t.TempDir(),
+		SSHUser:     DefaultSSHUserName,
+		SSHPort:     22,
+	}}
+
+	flags := testFlags{}
+	for _, flag := range d.GetCreateFlags() {
+		switch flag := flag.(type) {
+		case mcnflag.StringFlag:
+			flags[flag.Name] = flag.Value
+		case mcnflag.StringSliceFlag:
+			flags[flag.Name] = flag.Value
+		case mcnflag.IntFlag:
+			flags[flag.Name] = flag.Value
+		}
+	}
+	for key, value := range overrides {
+		flags[key] = value
+	}
+
+	if err := d.SetConfigFromFlags(flags); err != nil {
+		t.Fatal(err)
+	}
+	d.client = cloud
+	return d
+}
+
+// newTestMachine returns a driver whose machine was created on cloud
+func newTestMachine(t *testing.T, cloud *fakeCloud, overrides testFlags) *Driver {
+	d := newTestDriver(t, cloud, overrides)
+	if err := d.PreCreateCheck(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Create(); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// checkError fails the test unless err matches expected, a message
+// substring. An empty expected message means no error
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+func TestPreCreateCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(cloud *fakeCloud)
+		flags    testFlags
+		expected string
+		check    func(t *testing.T, d *Driver)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, d *Driver) {
+				if d.ProjectID != "project-1" || d.FlavorID != "flavor-1" || d.ImageID != "image-1" {
+					t.Errorf("unexpected ids: project %s, flavor %s, image %s", d.ProjectID, d.FlavorID, d.ImageID)
+				}
+				if !strings.HasPrefix(d.KeyPairName, "test-machine-") {
+					t.Errorf("unexpected key pair name %s", d.KeyPairName)
+				}
+				if len(d.NetworkIDs) != 0 {
+					t.Errorf("unexpected networks %v", d.NetworkIDs)
+				}
+			},
+		},
+		{
+			name:  "project by name",
+			setup: func(cloud *fakeCloud) { cloud.Projects["project-2"] = &Project{ID: "project-2", Name: "other"} },
+			flags: testFlags{"ovh-project": "docker-machine"},
+			check: func(t *testing.T, d *Driver) {
+				if d.ProjectID != "project-1" {
+					t.Errorf("unexpected project %s", d.ProjectID)
+				}
+			},
+		},
+		{
+			name:     "several projects",
+			setup:    func(cloud *fakeCloud) { cloud.Projects["project-2"] = &Project{ID: "project-2", Name: "other"} },
+			expected: "Multiple Cloud project found (docker-machine, other)",
+		},
+		{
+			name:     "no project",
+			setup:    func(cloud *fakeCloud) { cloud.Projects = map[string]*Project{} },
+			expected: "No Cloud project could be found",
+		},
+		{
+			name:     "unknown project",
+			flags:    testFlags{"ovh-project": "unknown"},
+			expected: "Project 'unknown' does not exist",
+		},
+		{
+			name:     "unknown region",
+			flags:    testFlags{"ovh-region": "XXX1"},
+			expected: "Invalid region XXX1",
+		},
+		{
+			name:     "non linux flavor",
+			flags:    testFlags{"ovh-flavor": "win-ssd-1"},
+			expected: "Flavor 'win-ssd-1' does not exist",
+		},
+		{
+			name:     "non linux image",
+			flags:    testFlags{"ovh-image": "Windows 2016"},
+			expected: "Image 'Windows 2016' does not exist",
+		},
+		{
+			name:     "invalid billing period",
+			flags:    testFlags{"ovh-billing-period": "yearly"},
+			expected: "Invalid billing period 'yearly'",
+		},
+		{
+			name:     "invalid stop mode",
+			flags:    testFlags{"ovh-stop-mode": "hibernate"},
+			expected: "Invalid stop mode 'hibernate'",
+		},
+		{
+			name:     "exclusive user data",
+			flags:    testFlags{"ovh-userdata": "user-data.txt", "ovh-userdata-inline": "#!/bin/sh"},
+			expected: "mutually exclusive",
+		},
+		{
+			name:     "invalid volume",
+			flags:    testFlags{"ovh-volume": []string{"big"}},
+			expected: "Invalid volume 'big'",
+		},
+		{
+			name: "existing private network",
+			setup: func(cloud *fakeCloud) {
+				cloud.Networks["network-9"] = &fakeNetwork{Network: Network{ID: "network-9", Name: "backend", VlanID: 9, Status: "ACTIVE"}}
+			},
+			flags: testFlags{"ovh-private-network": []string{"backend"}},
+			check: func(t *testing.T, d *Driver) {
+				if len(d.NetworkIDs) != 2 || d.NetworkIDs[0] != "network-9" || d.NetworkIDs[1] != "ext-net" {
+					t.Errorf("unexpected networks %v", d.NetworkIDs)
+				}
+			},
+		},
+		{
+			name: "several private networks",
+			setup: func(cloud *fakeCloud) {
+				cloud.Networks["network-8"] = &fakeNetwork{Network: Network{ID: "network-8", Name: "frontend", VlanID: 8, Status: "ACTIVE"}}
+				cloud.Networks["network-9"] = &fakeNetwork{Network: Network{ID: "network-9", Name: "backend", VlanID: 9, Status: "ACTIVE"}}
+			},
+			flags: testFlags{"ovh-private-network": []string{"backend", "8"}},
+			check: func(t *testing.T, d *Driver) {
+				if len(d.NetworkIDs) != 3 || d.NetworkIDs[0] != "network-9" || d.NetworkIDs[1] != "network-8" || d.NetworkIDs[2] != "ext-net" {
+					t.Errorf("unexpected networks %v", d.NetworkIDs)
+				}
+				if len(d.PrivateNetworks) != 2 || d.PrivateNetworks[1].Name != "frontend" || d.PrivateNetworks[1].VlanID != 8 {
+					t.Errorf("unexpected private networks %+v", d.PrivateNetworks)
+				}
+			},
+		},
+		{
+			name:     "missing private network",
+			flags:    testFlags{"ovh-private-network": []string{"backend"}},
+			expected: "Invalid private network backend",
+		},
+		{
+			name:  "missing private network created",
+			flags: testFlags{"ovh-private-network": []string{"backend"}, "ovh-private-network-create": true},
+			check: func(t *testing.T, d *Driver) {
+				if len(d.PrivateNetworks) != 1 || !d.PrivateNetworks[0].Created || d.PrivateNetworks[0].ID != "" {
+					t.Errorf("unexpected private networks %+v", d.PrivateNetworks)
+				}
+			},
+		},
+		{
+			name:     "two missing private networks created",
+			flags:    testFlags{"ovh-private-network": []string{"backend", "frontend"}, "ovh-private-network-create": true},
+			expected: "Only one private network may be created per machine",
+		},
+		{
+			name:     "invalid private network subnet",
+			flags:    testFlags{"ovh-private-network": []string{"backend"}, "ovh-private-network-create": true, "ovh-private-network-cidr": "2001:db8::/64"},
+			expected: "Invalid private network subnet '2001:db8::/64'",
+		},
+		{
+			name: "private only",
+			setup: func(cloud *fakeCloud) {
+				cloud.Networks["network-9"] = &fakeNetwork{Network: Network{ID: "network-9", Name: "backend", VlanID: 9, Status: "ACTIVE"}}
+			},
+			flags: testFlags{"ovh-private-network": []string{"backend"}, "ovh-no-public-network": true},
+			check: func(t *testing.T, d *Driver) {
+				if len(d.NetworkIDs) != 1 || d.NetworkIDs[0] != "network-9" {
+					t.Errorf("unexpected networks %v", d.NetworkIDs)
+				}
+			},
+		},
+		{
+			name:     "private only without private network",
+			flags:    testFlags{"ovh-no-public-network": true},
+			expected: "need at least one private network",
+		},
+		{
+			name: "static private IP",
+			setup: func(cloud *fakeCloud) {
+				cloud.Networks["network-9"] = &fakeNetwork{
+					Network: Network{ID: "network-9", Name: "backend", VlanID: 9, Status: "ACTIVE"},
+					Subnets: Subnets{{ID: "subnet-9", CIDR: "10.0.0.0/24"}},
+				}
+			},
+			flags: testFlags{"ovh-private-network": []string{"backend"}, "ovh-private-ip": "10.0.0.50"},
+			check: func(t *testing.T, d *Driver) {
+				if networks := d.getNetworkParams(); len(networks) != 2 || networks[0].IP != "10.0.0.50" || networks[1].IP != "" {
+					t.Errorf("unexpected instance networks %+v", networks)
+				}
+			},
+		},
+		{
+			name:  "static private IP on created private network",
+			flags: testFlags{"ovh-private-network": []string{"backend"}, "ovh-private-network-create": true, "ovh-private-ip": "192.168.0.10"},
+			check: func(t *testing.T, d *Driver) {
+				if d.PrivateNetworks[0].IP != "192.168.0.10" {
+					t.Errorf("unexpected private networks %+v", d.PrivateNetworks)
+				}
+			},
+		},
+		{
+			name: "static private IP outside of the subnets",
+			setup: func(cloud *fakeCloud) {
+				cloud.Networks["network-9"] = &fakeNetwork{
+					Network: Network{ID: "network-9", Name: "backend", VlanID: 9, Status: "ACTIVE"},
+					Subnets: Subnets{{ID: "subnet-9", CIDR: "10.0.0.0/24"}},
+				}
+			},
+			flags:    testFlags{"ovh-private-network": []string{"backend"}, "ovh-private-ip": "10.1.0.50"},
+			expected: "Private IP 10.1.0.50 does not belong to private network backend",
+		},
+		{
+			name: "static private IP already used",
+			setup: func(cloud *fakeCloud) {
+				cloud.Networks["network-9"] = &fakeNetwork{
+					Network: Network{ID: "network-9", Name: "backend", VlanID: 9, Status: "ACTIVE"},
+					Subnets: Subnets{{ID: "subnet-9", CIDR: "10.0.0.0/24"}},
+				}
+				cloud.Instances["instance-0"] = &fakeInstance{Instance: Instance{
+					ID: "instance-0", Name: "other-machine", Region: "GRA1", Status: "ACTIVE",
+					IPAddresses: IPs{{IP: "10.0.0.50", Type: "private", Version: 4, NetworkID: "network-9"}},
+				}}
+			},
+			flags:    testFlags{"ovh-private-network": []string{"backend"}, "ovh-private-ip": "10.0.0.50"},
+			expected: "Private IP 10.0.0.50 is already used by instance other-machine",
+		},
+		{
+			name:     "static private IP without private network",
+			flags:    testFlags{"ovh-private-ip": "10.0.0.50"},
+			expected: "A static private IP needs a private network",
+		},
+		{
+			name:     "invalid backup schedule",
+			flags:    testFlags{"ovh-backup-cron": "daily"},
+			expected: "Invalid backup schedule 'daily'",
+		},
+		{
+			name:     "invalid backup rotation",
+			flags:    testFlags{"ovh-backup-cron": "0 3 * * *", "ovh-backup-rotation": 0},
+			expected: "Invalid backup rotation 0",
+		},
+		{
+			name:     "invalid allowed network",
+			flags:    testFlags{"ovh-allowed-cidr": []string{"203.0.113.0"}},
+			expected: "Invalid allowed network '203.0.113.0'",
+		},
+		{
+			name: "unused floating IP",
+			setup: func(cloud *fakeCloud) {
+				cloud.FailoverIPs = FailoverIPs{
+					{ID: "failover-1", IP: "198.51.100.6/32", RoutedTo: "instance-0", Status: "ok"},
+					{ID: "failover-2", IP: "198.51.100.7/32", Status: "ok"},
+				}
+			},
+			flags: testFlags{"ovh-floating-ip": "auto"},
+			check: func(t *testing.T, d *Driver) {
+				if d.FloatingIP != "198.51.100.7" || d.FloatingIPID != "failover-2" || !d.FloatingIPAuto {
+					t.Errorf("unexpected floating IP %s (%s)", d.FloatingIP, d.FloatingIPID)
+				}
+			},
+		},
+		{
+			name: "floating IP",
+			setup: func(cloud *fakeCloud) {
+				cloud.FailoverIPs = FailoverIPs{{ID: "failover-1", IP: "198.51.100.6/32", RoutedTo: "instance-0", Status: "ok"}}
+			},
+			flags: testFlags{"ovh-floating-ip": "198.51.100.6"},
+			check: func(t *testing.T, d *Driver) {
+				if d.FloatingIP != "198.51.100.6" || d.FloatingIPID != "failover-1" || d.FloatingIPAuto {
+					t.Errorf("unexpected floating IP %s (%s)", d.FloatingIP, d.FloatingIPID)
+				}
+			},
+		},
+		{
+			name: "no unused floating IP",
+			setup: func(cloud *fakeCloud) {
+				cloud.FailoverIPs = FailoverIPs{{ID: "failover-1", IP: "198.51.100.6/32", RoutedTo: "instance-0", Status: "ok"}}
+			},
+			flags:    testFlags{"ovh-floating-ip": "auto"},
+			expected: "No unused failover IP in project",
+		},
+		{
+			name:     "unknown floating IP",
+			flags:    testFlags{"ovh-floating-ip": "198.51.100.6"},
+			expected: "Failover IP '198.51.100.6' does not exist on OVH cloud",
+		},
+		{
+			name: "floating IP without public network",
+			setup: func(cloud *fakeCloud) {
+				cloud.Networks["network-9"] = &fakeNetwork{Network: Network{ID: "network-9", Name: "backend", VlanID: 9, Status: "ACTIVE"}}
+			},
+			flags:    testFlags{"ovh-private-network": []string{"backend"}, "ovh-no-public-network": true, "ovh-floating-ip": "auto"},
+			expected: "Floating IPs need a public network",
+		},
+		{
+			name:     "invalid IP preference",
+			flags:    testFlags{"ovh-ip-preference": "public"},
+			expected: "Invalid IP preference 'public'",
+		},
+		{
+			name:     "private IP preference without private network",
+			flags:    testFlags{"ovh-ip-preference": "private"},
+			expected: "IP preference 'private' needs a private network",
+		},
+		{
+			name: "public IP preference without public network",
+			setup: func(cloud *fakeCloud) {
+				cloud.Networks["network-9"] = &fakeNetwork{Network: Network{ID: "network-9", Name: "backend", VlanID: 9, Status: "ACTIVE"}}
+			},
+			flags:    testFlags{"ovh-private-network": []string{"backend"}, "ovh-no-public-network": true, "ovh-ip-preference": "public-v4"},
+			expected: "IP preference 'public-v4' needs a public network",
+		},
+		{
+			name: "IPv6 without public network",
+			setup: func(cloud *fakeCloud) {
+				cloud.Networks["network-9"] = &fakeNetwork{Network: Network{ID: "network-9", Name: "backend", VlanID: 9, Status: "ACTIVE"}}
+			},
+			flags:    testFlags{"ovh-private-network": []string{"backend"}, "ovh-no-public-network": true, "ovh-ipv6": true},
+			expected: "IPv6 needs a public network",
+		},
+		{
+			name:  "IPv6 preference",
+			flags: testFlags{"ovh-ip-preference": "public-v6"},
+			check: func(t *testing.T, d *Driver) {
+				if !d.IPv6 {
+					t.Errorf("IPv6 is not required")
+				}
+			},
+		},
+		{
+			name:     "invalid Docker IPv6 subnet",
+			flags:    testFlags{"ovh-ipv6-docker-cidr": "10.0.0.0/24"},
+			expected: "Invalid Docker IPv6 subnet '10.0.0.0/24'",
+		},
+		{
+			name: "IPv6 in region without IPv6",
+			setup: func(cloud *fakeCloud) {
+				cloud.RegionServices = cloud.RegionServices[:2]
+			},
+			flags:    testFlags{"ovh-ipv6-docker": true},
+			expected: "IPv6 is not available in region GRA1",
+		},
+		{
+			name:  "Docker IPv6 subnet",
+			flags: testFlags{"ovh-ipv6-docker-cidr": "2001:db8:1::1/80"},
+			check: func(t *testing.T, d *Driver) {
+				if !d.IPv6 || !d.IPv6Docker || d.IPv6DockerCIDR != "2001:db8:1::/80" {
+					t.Errorf("IPv6 is not enabled in Docker: %t, %s", d.IPv6Docker, d.IPv6DockerCIDR)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cloud := newFakeCloud()
+			if test.setup != nil {
+				test.setup(cloud)
+			}
+			d := newTestDriver(t, cloud, test.flags)
+
+			err := d.PreCreateCheck()
+			checkError(t, err, test.expected)
+			if len(cloud.Calls) > 0 {
+				t.Errorf("PreCreateCheck changed the project: %v", cloud.Calls)
+			}
+			if err == nil && test.check != nil {
+				test.check(t, d)
+			}
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	cloud := newFakeCloud()
+	d := newTestMachine(t, cloud, testFlags{
+		"ovh-private-network":        []string{"backend"},
+		"ovh-private-network-create": true,
+		"ovh-volume-size":            10,
+		"ovh-userdata-inline":        "#!/bin/sh\necho hello",
+	})
+
+	instance, ok := cloud.Instances[d.InstanceID]
+	if !ok || instance.Status != "ACTIVE" {
+		t.Fatalf("instance %s is not active: %+v", d.InstanceID, instance)
+	}
+	if instance.Sshkey.ID != d.KeyPairID || cloud.Sshkeys[d.KeyPairID] == nil {
+		t.Errorf("instance uses key %s, expected uploaded key %s", instance.Sshkey.ID, d.KeyPairID)
+	}
+	if instance.Flavor.ID != "flavor-1" || instance.Image.ID != "image-1" {
+		t.Errorf("unexpected flavor %s and image %s", instance.Flavor.ID, instance.Image.ID)
+	}
+
+	// The created private network comes first, with its subnet
+	network := d.PrivateNetworks[0]
+	if cloud.Networks[network.ID] == nil || len(cloud.Networks[network.ID].Subnets) != 1 {
+		t.Fatalf("private network %+v was not created with a subnet", network)
+	}
+	if len(instance.NetworkParams) != 2 || instance.NetworkParams[0].ID != network.ID || instance.NetworkParams[1].ID != "ext-net" {
+		t.Errorf("unexpected instance networks %+v", instance.NetworkParams)
+	}
+	if len(d.PrivateNetworks) != 1 || d.PrivateNetworks[0].IP != instance.IPAddresses[0].IP {
+		t.Errorf("private IP is not saved: %+v", d.PrivateNetworks)
+	}
+
+	// User data is merged with the vRack configuration
+	if !strings.Contains(instance.UserData, "echo hello") || !strings.Contains(instance.UserData, "Configure vRack interfaces") {
+		t.Errorf("unexpected user data %q", instance.UserData)
+	}
+
+	if ip, _ := d.GetIP(); ip != instance.IPAddresses[1].IP {
+		t.Errorf("unexpected IP %s, expected public IP %s", ip, instance.IPAddresses[1].IP)
+	}
+
+	if len(d.VolumeIDs) != 1 {
+		t.Fatalf("unexpected volumes %v", d.VolumeIDs)
+	}
+	volume := cloud.Volumes[d.VolumeIDs[0]]
+	if volume.Size != 10 || volume.Status != "in-use" || len(volume.AttachedTo) != 1 || volume.AttachedTo[0] != d.InstanceID {
+		t.Errorf("volume is not attached: %+v", volume.Volume)
+	}
+}
+
+func TestCreatePrivateOnly(t *testing.T) {
+	cloud := newFakeCloud()
+	cloud.Networks["network-9"] = &fakeNetwork{Network: Network{ID: "network-9", Name: "backend", VlanID: 9, Status: "ACTIVE"}}
+	d := newTestMachine(t, cloud, testFlags{"ovh-private-network": []string{"backend"}, "ovh-no-public-network": true})
+
+	instance := cloud.Instances[d.InstanceID]
+	if len(instance.NetworkParams) != 1 || len(instance.IPAddresses) != 1 {
+		t.Fatalf("unexpected instance networks %+v, addresses %v", instance.NetworkParams, instance.IPAddresses)
+	}
+	if ip, _ := d.GetIP(); ip != instance.IPAddresses[0].IP {
+		t.Errorf("unexpected IP %s, expected private IP %s", ip, instance.IPAddresses[0].IP)
+	}
+}
+
+func TestCreateFloatingIP(t *testing.T) {
+	cloud := newFakeCloud()
+	cloud.FailoverIPs = FailoverIPs{{ID: "failover-1", IP: "198.51.100.7/32", Status: "ok"}}
+	d := newTestMachine(t, cloud, testFlags{"ovh-floating-ip": "auto"})
+
+	if cloud.FailoverIPs[0].RoutedTo != d.InstanceID {
+		t.Errorf("floating IP is routed to %q, expected %s", cloud.FailoverIPs[0].RoutedTo, d.InstanceID)
+	}
+	if !strings.Contains(cloud.Instances[d.InstanceID].UserData, floatingIPScriptPath) {
+		t.Errorf("floating IP is not configured by user data")
+	}
+	if ip, _ := d.GetIP(); ip != "198.51.100.7" {
+		t.Errorf("unexpected IP %s, expected floating IP 198.51.100.7", ip)
+	}
+}
+
+func TestCreateIPv6(t *testing.T) {
+	tests := []struct {
+		name   string
+		flags  testFlags
+		subnet string
+	}{
+		{name: "IPv6 only", flags: testFlags{"ovh-ipv6": true}},
+		{name: "Docker IPv6 subnet", flags: testFlags{"ovh-ipv6-docker-cidr": "2001:db8:1::1/80"}, subnet: "2001:db8:1::/80"},
+		{name: "instance IPv6 block", flags: testFlags{"ovh-ipv6-docker": true}, subnet: "2001:db8:0:0:ffff::/80"},
+	}
+
+	defer func(run func(d *Driver, script string) error) { runSSHScript = run }(runSSHScript)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var scripts []string
+			runSSHScript = func(d *Driver, script string) error {
+				scripts = append(scripts, script)
+				return nil
+			}
+
+			cloud := newFakeCloud()
+			d := newTestMachine(t, cloud, test.flags)
+			if d.IPv6Address != cloud.Instances[d.InstanceID].IPAddresses[1].IP {
+				t.Errorf("unexpected IPv6 address %q", d.IPv6Address)
+			}
+			if test.subnet == "" {
+				if len(scripts) != 0 {
+					t.Errorf("IPv6 is enabled in Docker: %v", scripts)
+				}
+				return
+			}
+			if len(scripts) != 1 || !strings.Contains(scripts[0], `"fixed-cidr-v6": "`+test.subnet+`"`) {
+				t.Errorf("IPv6 is not enabled in Docker with subnet %s: %v", test.subnet, scripts)
+			}
+		})
+	}
+}
+
+// liveResources lists the resources left in cloud, deleted instances excluded
+func liveResources(cloud *fakeCloud) (resources []string) {
+	for id, instance := range cloud.Instances {
+		if instance.Status != "DELETING" && instance.Status != fakeGone {
+			resources = append(resources, id)
+		}
+	}
+	for id := range cloud.Sshkeys {
+		resources = append(resources, id)
+	}
+	for id := range cloud.Volumes {
+		resources = append(resources, id)
+	}
+	for id := range cloud.Networks {
+		resources = append(resources, id)
+	}
+	for id := range cloud.Workflows {
+		resources = append(resources, id)
+	}
+	return resources
+}
+
+func TestCreateRollback(t *testing.T) {
+	flags := testFlags{
+		"ovh-private-network":        []string{"backend"},
+		"ovh-private-network-create": true,
+		"ovh-volume-size":            10,
+		"ovh-backup-cron":            "0 3 * * *",
+	}
+
+	for _, method := range []string{"CreateSubnet", "CreateSshkey", "CreateInstance", "GetInstance", "CreateVolume", "AttachVolume", "CreateBackupWorkflow"} {
+		t.Run(method, func(t *testing.T) {
+			cloud := newFakeCloud()
+			d := newTestDriver(t, cloud, flags)
+			checkError(t, d.PreCreateCheck(), "")
+
+			cloud.Failures[method] = fmt.Errorf("%s failed", method)
+			checkError(t, d.Create(), method+" failed")
+
+			if resources := liveResources(cloud); len(resources) > 0 {
+				t.Errorf("resources left after rollback: %v", resources)
+			}
+		})
+	}
+
+	t.Run("keep on failure", func(t *testing.T) {
+		cloud := newFakeCloud()
+		d := newTestDriver(t, cloud, testFlags{"ovh-volume-size": 10, "ovh-keep-on-failure": true})
+		checkError(t, d.PreCreateCheck(), "")
+
+		cloud.Failures["AttachVolume"] = fmt.Errorf("AttachVolume failed")
+		checkError(t, d.Create(), "AttachVolume failed")
+
+		if resources := liveResources(cloud); len(resources) != 3 {
+			t.Errorf("expected the instance, key and volume to be kept, got %v", resources)
+		}
+	})
+}
+
+func TestRemove(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(cloud *fakeCloud)
+		flags testFlags
+		kept  int
+	}{
+		{
+			name:  "everything",
+			flags: testFlags{"ovh-volume-size": 10, "ovh-private-network": []string{"backend"}, "ovh-private-network-create": true},
+		},
+		{
+			name:  "keep volumes",
+			flags: testFlags{"ovh-volume-size": 10, "ovh-volume-keep": true},
+			kept:  1,
+		},
+		{
+			name: "keep shared SSH key",
+			setup: func(cloud *fakeCloud) {
+				cloud.Sshkeys["key-0"] = &Sshkey{ID: "key-0", Name: "shared"}
+			},
+			flags: testFlags{"ovh-ssh-key": "shared"},
+			kept:  1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cloud := newFakeCloud()
+			if test.setup != nil {
+				test.setup(cloud)
+			}
+			d := newTestMachine(t, cloud, test.flags)
+
+			checkError(t, d.Remove(), "")
+
+			resources := liveResources(cloud)
+			if len(resources) != test.kept {
+				t.Errorf("expected %d resources to be kept, got %v", test.kept, resources)
+			}
+			for _, volume := range cloud.Volumes {
+				if volume.Status != "available" || len(volume.AttachedTo) > 0 {
+					t.Errorf("kept volume is still attached: %+v", volume.Volume)
+				}
+			}
+		})
+	}
+}
+
+func TestGetPreferredIP(t *testing.T) {
+	instanceIPs := IPs{
+		{IP: "10.0.0.4", Type: "private", Version: 4},
+		{IP: "203.0.113.4", Type: "public", Version: 4},
+		{IP: "2001:db8::4", Type: "public", Version: 6},
+	}
+	tests := []struct {
+		name       string
+		preference string
+		floatingIP string
+		expected   string
+	}{
+		{name: "default", expected: "203.0.113.4"},
+		{name: "public-v6", preference: "public-v6", expected: "2001:db8::4"},
+		{name: "private", preference: "private", expected: "10.0.0.4"},
+		{name: "floating IPv4", floatingIP: "198.51.100.7", expected: "198.51.100.7"},
+		{name: "floating IPv4 for public-v6", preference: "public-v6", floatingIP: "198.51.100.7", expected: "2001:db8::4"},
+		{name: "floating IPv4 for private", preference: "private", floatingIP: "198.51.100.7", expected: "10.0.0.4"},
+		{name: "floating IPv6", preference: "public-v6", floatingIP: "2001:db8:1::7", expected: "2001:db8:1::7"},
+		{name: "floating IPv6 for public-v4", preference: "public-v4", floatingIP: "2001:db8:1::7", expected: "203.0.113.4"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := &Driver{IPPreference: test.preference, FloatingIP: test.floatingIP, InstanceIPs: instanceIPs}
+			if ip := d.getPreferredIP(); ip != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, ip)
+			}
+		})
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		expected string
+	}{
+		{name: "new name", existing: "other-snapshot"},
+		{name: "taken name", existing: "my-snapshot", expected: "Snapshot name 'my-snapshot' is already used by image snapshot-0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cloud := newFakeCloud()
+			d := newTestMachine(t, cloud, nil)
+			cloud.Snapshots = Images{{ID: "snapshot-0", Name: test.existing, Region: "GRA1", OS: "linux", Status: "active", Visibility: "private"}}
+			cloud.Calls = nil
+
+			image, err := d.Snapshot("my-snapshot")
+			checkError(t, err, test.expected)
+			if test.expected != "" {
+				if len(cloud.Calls) != 0 || len(cloud.Snapshots) != 1 {
+					t.Errorf("snapshot was created: %v", cloud.Calls)
+				}
+				return
+			}
+			if image == nil || image.ID == "snapshot-0" || image.Name != "my-snapshot" || image.Visibility != "private" || len(cloud.Snapshots) != 2 {
+				t.Fatalf("unexpected snapshot %+v", image)
+			}
+
+			// New machines may be created from the snapshot
+			clone := newTestDriver(t, cloud, testFlags{"ovh-image": "my-snapshot"})
+			checkError(t, clone.PreCreateCheck(), "")
+			if clone.ImageID != image.ID {
+				t.Errorf("unexpected image %s, expected snapshot %s", clone.ImageID, image.ID)
+			}
+		})
+	}
+}
+
+func TestStopStart(t *testing.T) {
+	tests := []struct {
+		name     string
+		flags    testFlags
+		outcomes map[string][]string
+		stop     string
+		stopped  string
+		start    string
+	}{
+		{name: "stop", stop: "StopInstance", stopped: "SHUTOFF", start: "StartInstance"},
+		{
+			name:     "shelve",
+			flags:    testFlags{"ovh-stop-mode": "shelve"},
+			outcomes: map[string][]string{"ShelveInstance": {"SHELVED"}},
+			stop:     "ShelveInstance",
+			stopped:  "SHELVED",
+			start:    "UnshelveInstance",
+		},
+		{
+			name:    "shelve offloaded",
+			flags:   testFlags{"ovh-stop-mode": "shelve"},
+			stop:    "ShelveInstance",
+			stopped: "SHELVED_OFFLOADED",
+			start:   "UnshelveInstance",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cloud := newFakeCloud()
+			d := newTestMachine(t, cloud, test.flags)
+			for method, outcome := range test.outcomes {
+				cloud.Outcomes[method] = outcome
+			}
+			instance := cloud.Instances[d.InstanceID]
+
+			cloud.Calls = nil
+			checkError(t, d.Stop(), "")
+			if len(cloud.Calls) != 1 || cloud.Calls[0] != test.stop+" "+d.InstanceID {
+				t.Errorf("expected %s, got calls %v", test.stop, cloud.Calls)
+			}
+			if st, err := d.GetState(); err != nil || st != state.Stopped || instance.Status != test.stopped {
+				t.Errorf("expected state %s in status %s, got %s in status %s (%v)", state.Stopped, test.stopped, st, instance.Status, err)
+			}
+
+			cloud.Calls = nil
+			checkError(t, d.Start(), "")
+			if len(cloud.Calls) != 1 || cloud.Calls[0] != test.start+" "+d.InstanceID {
+				t.Errorf("expected %s, got calls %v", test.start, cloud.Calls)
+			}
+			if st, err := d.GetState(); err != nil || st != state.Running {
+				t.Errorf("expected state %s, got %s (%v)", state.Running, st, err)
+			}
+		})
+	}
+}
+
+func TestKill(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures []string
+		outcomes map[string][]string
+		settle   bool
+		calls    []string
+		status   string
+		expected string
+	}{
+		{
+			name:   "hard stop",
+			calls:  []string{"StopInstance"},
+			status: "SHUTOFF",
+		},
+		{
+			name:     "stop failure",
+			failures: []string{"StopInstance"},
+			outcomes: map[string][]string{"RebootInstance": nil},
+			calls:    []string{"StopInstance", "RebootInstance"},
+			status:   "HARD_REBOOT",
+		},
+		{
+			name:     "stop wait failure",
+			outcomes: map[string][]string{"StopInstance": {"ERROR"}, "RebootInstance": nil},
+			calls:    []string{"StopInstance", "RebootInstance"},
+			status:   "HARD_REBOOT",
+		},
+		{
+			name:     "reboot failure",
+			failures: []string{"StopInstance", "RebootInstance"},
+			calls:    []string{"StopInstance", "RebootInstance"},
+			status:   "ACTIVE",
+			expected: "RebootInstance failed",
+		},
+		{
+			name:     "ERROR after kill",
+			outcomes: map[string][]string{"StopInstance": {"ERROR"}, "RebootInstance": {"ERROR"}},
+			settle:   true,
+			calls:    []string{"StopInstance", "RebootInstance"},
+			status:   "ERROR",
+			expected: "Instance instance-2 is in ERROR state after kill",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cloud := newFakeCloud()
+			d := newTestMachine(t, cloud, nil)
+			for _, method := range test.failures {
+				cloud.Failures[method] = fmt.Errorf("%s failed", method)
+			}
+			for method, outcome := range test.outcomes {
+				cloud.Outcomes[method] = outcome
+			}
+			cloud.Settle = test.settle
+			cloud.Calls = nil
+
+			checkError(t, d.Kill(), test.expected)
+
+			var expected []string
+			for _, method := range test.calls {
+				expected = append(expected, method+" "+d.InstanceID)
+			}
+			if strings.Join(cloud.Calls, ",") != strings.Join(expected, ",") {
+				t.Errorf("expected calls %v, got %v", expected, cloud.Calls)
+			}
+			if status := cloud.Instances[d.InstanceID].Status; status != test.status {
+				t.Errorf("expected status %s, got %s", test.status, status)
+			}
+		})
+	}
+}
+
+func TestGetState(t *testing.T) {
+	tests := []struct {
+		status   string
+		failure  error
+		expected state.State
+		err      string
+	}{
+		{status: "ACTIVE", expected: state.Running},
+		{status: "BUILD", expected: state.Starting},
+		{status: "SHUTOFF", expected: state.Stopped},
+		{status: "SHELVED_OFFLOADED", expected: state.Stopped},
+		{status: "ERROR", expected: state.Error},
+		{status: "RESCUE", expected: state.Running},
+		{status: "DELETED", err: "Machine test-machine does not exist on OVH cloud"},
+		{status: fakeGone, err: "Machine test-machine does not exist on OVH cloud"},
+		{status: "SOFT_DELETED", err: "Unknown OVH instance status 'SOFT_DELETED'"},
+		{status: "ACTIVE", failure: &ovh.APIError{Code: 500, Message: "internal error"}, err: "internal error"},
+		{status: "ACTIVE", failure: &ovh.APIError{Code: 404, Message: "not found"}, err: "Machine test-machine does not exist on OVH cloud"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.status, func(t *testing.T) {
+			cloud := newFakeCloud()
+			d := newTestMachine(t, cloud, nil)
+			cloud.Instances[d.InstanceID].Status = test.status
+			cloud.Failures["GetInstance"] = test.failure
+
+			st, err := d.GetState()
+			checkError(t, err, test.err)
+			if st != test.expected {
+				t.Errorf("expected state %s, got %s", test.expected, st)
+			}
+		})
+	}
+}
+
+func TestRemoveBackups(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags testFlags
+		kept  []string
+	}{
+		{
+			name:  "keep backups",
+			flags: testFlags{"ovh-backup-cron": "0 3 * * *"},
+			kept:  []string{"-2017-06-01", "other-snapshot"},
+		},
+		{
+			name:  "purge backups",
+			flags: testFlags{"ovh-backup-cron": "0 3 * * *", "ovh-backup-purge": true},
+			kept:  []string{"other-snapshot"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cloud := newFakeCloud()
+			d := newTestMachine(t, cloud, test.flags)
+			if d.BackupName != "test-machine-backup-"+d.InstanceID || len(cloud.Workflows) != 1 {
+				t.Fatalf("backups are not scheduled: %v", cloud.Workflows)
+			}
+			for i, name := range []string{d.BackupName + "-2017-06-01", "other-snapshot"} {
+				cloud.Snapshots = append(cloud.Snapshots, Image{ID: fmt.Sprintf("snapshot-%d", i), Name: name, Region: "GRA1", OS: "linux", Status: "active"})
+			}
+
+			checkError(t, d.Remove(), "")
+
+			var kept []string
+			for _, snapshot := range cloud.Snapshots {
+				kept = append(kept, strings.TrimPrefix(snapshot.Name, d.BackupName))
+			}
+			if strings.Join(kept, ",") != strings.Join(test.kept, ",") || len(cloud.Workflows) != 0 {
+				t.Errorf("expected snapshots %v to be kept, got %v", test.kept, kept)
+			}
+		})
+	}
+}
+
+func TestRemoveBackupsOfOtherMachines(t *testing.T) {
+	cloud := newFakeCloud()
+	flags := testFlags{"ovh-backup-cron": "0 3 * * *", "ovh-backup-purge": true}
+	machines := map[string]*Driver{}
+	for _, name := range []string{"web", "web-backup"} {
+		d := newTestDriver(t, cloud, flags)
+		d.MachineName = name
+		checkError(t, d.PreCreateCheck(), "")
+		checkError(t, d.Create(), "")
+		machines[name] = d
+	}
+
+	web, other := machines["web"].BackupName, machines["web-backup"].BackupName
+	if web == other || !strings.Contains(web, machines["web"].InstanceID) {
+		t.Fatalf("backup names are not unique: %s, %s", web, other)
+	}
+	for i, name := range []string{
+		web + "-2017-06-01T03:00:00Z",
+		web + "-2017-06-02",
+		web + "-manual",
+		other + "-2017-06-01T03:00:00Z",
+	} {
+		cloud.Snapshots = append(cloud.Snapshots, Image{ID: fmt.Sprintf("snapshot-%d", i), Name: name, Region: "GRA1", OS: "linux", Status: "active"})
+	}
+
+	checkError(t, machines["web"].Remove(), "")
+
+	var kept []string
+	for _, snapshot := range cloud.Snapshots {
+		kept = append(kept, snapshot.Name)
+	}
+	expected := []string{web + "-manual", other + "-2017-06-01T03:00:00Z"}
+	if strings.Join(kept, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected snapshots %v to be kept, got %v", expected, kept)
+	}
+}
+
+func TestReinstall(t *testing.T) {
+	tests := []struct {
+		name     string
+		image    string
+		rescue   bool
+		settle   bool
+		failure  error
+		expected string
+	}{
+		{name: "snapshot", image: "my-snapshot"},
+		{name: "already rebuilt", image: "my-snapshot", settle: true},
+		{name: "unknown image", image: "Plan 9", expected: "Plan 9"},
+		{name: "rescue mode", image: "my-snapshot", rescue: true, expected: "Machine test-machine is in rescue mode"},
+		{name: "lookup failure", image: "my-snapshot", failure: &ovh.APIError{Code: 500, Message: "internal error"}, expected: "internal error"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cloud := newFakeCloud()
+			d := newTestMachine(t, cloud, nil)
+			cloud.Snapshots = Images{{ID: "snapshot-0", Name: "my-snapshot", Region: "GRA1", OS: "linux", Status: "active", Visibility: "private"}}
+			cloud.Calls = nil
+			d.Rescue = test.rescue
+			cloud.Settle = test.settle
+
+			cloud.Failures["GetInstance"] = test.failure
+			err := d.Reinstall(test.image)
+			checkError(t, err, test.expected)
+			instance := cloud.Instances[d.InstanceID]
+			if test.expected != "" {
+				if d.ImageID != "image-1" || (test.failure == nil && len(cloud.Calls) != 0) {
+					t.Errorf("machine was reinstalled: %s, %v", d.ImageID, cloud.Calls)
+				}
+				return
+			}
+			if d.ImageID != "snapshot-0" || instance.Image.ID != "snapshot-0" || instance.Status != "ACTIVE" {
+				t.Errorf("machine was not reinstalled: %s, %+v", d.ImageID, instance.Instance)
+			}
+			if ip, _ := d.GetIP(); ip != instance.IPAddresses[0].IP {
+				t.Errorf("unexpected IP %s after reinstall, expected %s", ip, instance.IPAddresses[0].IP)
+			}
+		})
+	}
+}
+
+func TestResize(t *testing.T) {
+	tests := []struct {
+		name     string
+		flavor   string
+		calls    []string
+		expected string
+	}{
+		{name: "bigger flavor", flavor: "vps-ssd-2", calls: []string{"ResizeInstance instance-2"}},
+		{name: "same flavor", flavor: "vps-ssd-1"},
+		{name: "smaller disk", flavor: "vps-ssd-0", expected: "Flavor vps-ssd-0 has a 5GB disk, which cannot hold the 10GB disk of flavor vps-ssd-1"},
+		{name: "unknown flavor", flavor: "vps-ssd-9", expected: "Flavor 'vps-ssd-9' does not exist on OVH cloud"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cloud := newFakeCloud()
+			cloud.Flavors = append(cloud.Flavors, Flavor{ID: "flavor-4", Name: "vps-ssd-0", Region: "GRA1", OS: "linux", Vcpus: 1, MemoryGB: 2, DiskSpaceGB: 5})
+			d := newTestMachine(t, cloud, nil)
+			cloud.Calls = nil
+
+			checkError(t, d.Resize(test.flavor), test.expected)
+			if strings.Join(cloud.Calls, ",") != strings.Join(test.calls, ",") {
+				t.Errorf("expected calls %v, got %v", test.calls, cloud.Calls)
+			}
+
+			expected := "vps-ssd-1"
+			if test.expected == "" {
+				expected = test.flavor
+			}
+			if d.FlavorName != expected || cloud.Instances[d.InstanceID].Flavor.Name != expected {
+				t.Errorf("expected flavor %s, got %s (%s), instance flavor %s", expected, d.FlavorName, d.FlavorID, cloud.Instances[d.InstanceID].Flavor.Name)
+			}
+			if flavor, _ := cloud.GetFlavorByName(d.ProjectID, d.RegionName, expected); d.FlavorID != flavor.ID {
+				t.Errorf("expected flavor id %s, got %s", flavor.ID, d.FlavorID)
+			}
+		})
+	}
+}
+
+func TestRescue(t *testing.T) {
+	cloud := newFakeCloud()
+	cloud.FailoverIPs = FailoverIPs{{ID: "failover-1", IP: "198.51.100.7/32", Status: "ok"}}
+	d := newTestMachine(t, cloud, testFlags{"ovh-floating-ip": "auto"})
+	publicIP := cloud.Instances[d.InstanceID].IPAddresses[0].IP
+
+	checkError(t, d.EnterRescue(""), "")
+	if status := cloud.Instances[d.InstanceID].Status; !d.Rescue || status != "RESCUE" || d.RescuePassword != "rescue-password" {
+		t.Errorf("machine is not in rescue mode: %s, %q", status, d.RescuePassword)
+	}
+	if host, _ := d.GetSSHHostname(); host != publicIP || d.GetSSHUsername() != RescueSSHUserName {
+		t.Errorf("expected SSH to the rescue system %s@%s, got %s@%s", RescueSSHUserName, publicIP, d.GetSSHUsername(), host)
+	}
+
+	// The machine cannot be reinstalled while in rescue mode
+	checkError(t, d.Reinstall("Ubuntu 16.04"), "is in rescue mode")
+
+	checkError(t, d.ExitRescue(), "")
+	if status := cloud.Instances[d.InstanceID].Status; d.Rescue || status != "ACTIVE" || d.RescuePassword != "" {
+		t.Errorf("machine is still in rescue mode: %s, %q", status, d.RescuePassword)
+	}
+	if host, _ := d.GetSSHHostname(); host != "198.51.100.7" || d.GetSSHUsername() != DefaultSSHUserName {
+		t.Errorf("expected SSH to the machine %s@198.51.100.7, got %s@%s", DefaultSSHUserName, d.GetSSHUsername(), host)
+	}
+}
+
+func TestAutoMonthlyBilling(t *testing.T) {
+	tests := []struct {
+		name     string
+		flags    testFlags
+		created  time.Duration
+		monthly  bool
+		switched bool
+		calls    []string
+		expected string
+	}{
+		{name: "never", created: 400 * time.Hour},
+		{name: "below threshold", flags: testFlags{"ovh-auto-monthly-after": "360h"}, created: 300 * time.Hour},
+		{
+			name:     "above threshold",
+			flags:    testFlags{"ovh-auto-monthly-after": "360h"},
+			created:  400 * time.Hour,
+			switched: true,
+			calls:    []string{"ActivateMonthlyBilling instance-2"},
+		},
+		{name: "already monthly", flags: testFlags{"ovh-auto-monthly-after": "360h", "ovh-billing-period": "monthly"}, created: 400 * time.Hour},
+		{name: "monthly on OVH", flags: testFlags{"ovh-auto-monthly-after": "360h"}, created: 400 * time.Hour, monthly: true, switched: true},
+		{name: "invalid creation date", flags: testFlags{"ovh-auto-monthly-after": "360h"}, expected: "Invalid creation date 'yesterday' for instance instance-2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cloud := newFakeCloud()
+			d := newTestMachine(t, cloud, test.flags)
+			instance := cloud.Instances[d.InstanceID]
+			instance.Created = "yesterday"
+			if test.created != 0 {
+				instance.Created = time.Now().Add(-test.created).UTC().Format(time.RFC3339)
+			}
+			if test.monthly && instance.MonthlyBilling == nil {
+				instance.MonthlyBilling = &MonthlyBilling{Since: instance.Created, Status: "ok"}
+			}
+			cloud.Calls = nil
+
+			switched, err := d.autoMonthlyBilling()
+			checkError(t, err, test.expected)
+			if switched != test.switched || strings.Join(cloud.Calls, ",") != strings.Join(test.calls, ",") {
+				t.Errorf("expected switched %t with calls %v, got %t with %v", test.switched, test.calls, switched, cloud.Calls)
+			}
+			if switched && (d.BillingPeriod != "monthly" || instance.MonthlyBilling == nil) {
+				t.Errorf("machine is not billed monthly: %s, %+v", d.BillingPeriod, instance.MonthlyBilling)
+			}
+		})
+	}
+}
@@ -0,0 +1,708 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+func init() {
+	// The fake backend settles in a few polls, do not wait between them
+	statusPollInterval = time.Millisecond
+}
+
+// fakeGone is the pending status of a deleted resource, removed on next read
+const fakeGone = "<gone>"
+
+// fakeInstance is an instance of the fake backend. Each read moves it to the
+// next pending status, modelling asynchronous OVH operations
+type fakeInstance struct {
+	Instance
+	Pending  []string
+	UserData string
+}
+
+// fakeVolume is a block storage volume of the fake backend
+type fakeVolume struct {
+	Volume
+	Pending []string
+}
+
+// fakeNetwork is a private network of the fake backend
+type fakeNetwork struct {
+	Network
+	Pending []string
+	Subnets Subnets
+}
+
+// fakeCloud is an in-memory OVH Cloud backend implementing CloudAPI
+type fakeCloud struct {
+	Projects        map[string]*Project
+	Regions         Regions
+	RegionServices  []RegionService
+	Flavors         Flavors
+	Images          Images
+	Snapshots       Images
+	PublicNetworkID string
+	Networks        map[string]*fakeNetwork
+	Sshkeys         map[string]*Sshkey
+	Instances       map[string]*fakeInstance
+	Volumes         map[string]*fakeVolume
+	FailoverIPs     FailoverIPs
+	Workflows       map[string]*BackupWorkflow
+	Quotas          Quotas
+
+	// Settle completes asynchronous operations at once, for callers that do
+	// not poll quickly
+	Settle bool
+	// Failures makes the named methods fail with the given error
+	Failures map[string]error
+	// Outcomes replaces the statuses an instance goes through after the
+	// named methods
+	Outcomes map[string][]string
+	// Calls logs the mutating calls, in order
+	Calls []string
+
+	lastID int
+}
+
+var _ CloudAPI = (*fakeCloud)(nil)
+
+// newFakeCloud returns a backend with a single project in GRA1 and BHS1, the
+// default flavor and image, and a public network
+func newFakeCloud() *fakeCloud {
+	return &fakeCloud{
+		Projects: map[string]*Project{
+			"project-1": {ID: "project-1", Name: "docker-machine", Status: "ok"},
+		},
+		Regions: Regions{"GRA1", "BHS1"},
+		RegionServices: []RegionService{
+			{Name: "instance", Status: "UP"},
+			{Name: "network", Status: "UP"},
+			{Name: "ipv6", Status: "UP"},
+		},
+		Flavors: Flavors{
+			{ID: "flavor-1", Name: "vps-ssd-1", Region: "GRA1", OS: "linux", Vcpus: 1, MemoryGB: 2, DiskSpaceGB: 10},
+			{ID: "flavor-2", Name: "vps-ssd-2", Region: "GRA1", OS: "linux", Vcpus: 1, MemoryGB: 4, DiskSpaceGB: 20},
+			{ID: "flavor-3", Name: "win-ssd-1", Region: "GRA1", OS: "windows", Vcpus: 1, MemoryGB: 2, DiskSpaceGB: 10},
+		},
+		Images: Images{
+			{ID: "image-1", Name: "Ubuntu 16.04", Region: "GRA1", OS: "linux", Status: "active", Visibility: "public"},
+			{ID: "image-2", Name: "Windows 2016", Region: "GRA1", OS: "windows", Status: "active", Visibility: "public"},
+		},
+		PublicNetworkID: "ext-net",
+		Networks:        map[string]*fakeNetwork{},
+		Sshkeys:         map[string]*Sshkey{},
+		Instances:       map[string]*fakeInstance{},
+		Volumes:         map[string]*fakeVolume{},
+		Workflows:       map[string]*BackupWorkflow{},
+		Failures:        map[string]error{},
+		Outcomes:        map[string][]string{},
+	}
+}
+
+func (f *fakeCloud) newID(kind string) string {
+	f.lastID++
+	return fmt.Sprintf("%s-%d", kind, f.lastID)
+}
+
+func (f *fakeCloud) call(method string, args ...interface{}) error {
+	f.Calls = append(f.Calls, strings.TrimSpace(fmt.Sprintln(append([]interface{}{method}, args...)...)))
+	return f.Failures[method]
+}
+
+func notFound(format string, args ...interface{}) error {
+	return &ovh.APIError{Code: 404, Message: fmt.Sprintf(format, args...)}
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &ovh.APIError{Code: 400, Message: fmt.Sprintf(format, args...)}
+}
+
+func (f *fakeCloud) checkProject(projectID string) error {
+	if _, ok := f.Projects[projectID]; !ok {
+		return notFound("project %s does not exist", projectID)
+	}
+	return nil
+}
+
+// advance moves a resource to its next pending status
+func advance(status *string, pending *[]string) {
+	if len(*pending) > 0 {
+		*status = (*pending)[0]
+		*pending = (*pending)[1:]
+	}
+}
+
+// settle completes the pending statuses of a resource, if the backend settles
+func (f *fakeCloud) settle(status *string, pending *[]string) {
+	for f.Settle && len(*pending) > 0 {
+		advance(status, pending)
+	}
+}
+
+// Projects
+
+func (f *fakeCloud) GetProjects() (projects Projects, err error) {
+	for id := range f.Projects {
+		projects = append(projects, id)
+	}
+	sort.Strings(projects)
+	return projects, f.Failures["GetProjects"]
+}
+
+func (f *fakeCloud) GetProject(projectID string) (*Project, error) {
+	if err := f.checkProject(projectID); err != nil {
+		return nil, err
+	}
+	project := *f.Projects[projectID]
+	return &project, nil
+}
+
+func (f *fakeCloud) GetProjectByName(projectName string) (*Project, error) {
+	for _, project := range f.Projects {
+		if project.ID == projectName || project.Name == projectName {
+			found := *project
+			return &found, nil
+		}
+	}
+	return nil, fmt.Errorf("Project '%s' does not exist on OVH cloud", projectName)
+}
+
+func (f *fakeCloud) GetRegions(projectID string) (Regions, error) {
+	return f.Regions, f.checkProject(projectID)
+}
+
+func (f *fakeCloud) GetRegion(projectID, region string) (*Region, error) {
+	if err := f.checkProject(projectID); err != nil {
+		return nil, err
+	}
+	for _, name := range f.Regions {
+		if name == region {
+			return &Region{Name: name, Status: "UP", Services: append([]RegionService{}, f.RegionServices...)}, nil
+		}
+	}
+	return nil, notFound("region %s does not exist", region)
+}
+
+func (f *fakeCloud) GetQuotas(projectID string) (Quotas, error) {
+	return f.Quotas, f.checkProject(projectID)
+}
+
+func (f *fakeCloud) GetFlavorByName(projectID, region, flavorName string) (*Flavor, error) {
+	for _, flavor := range f.Flavors {
+		if flavor.OS == "linux" && flavor.Region == region && (flavor.ID == flavorName || flavor.Name == flavorName) {
+			return &flavor, nil
+		}
+	}
+	return nil, fmt.Errorf("Flavor '%s' does not exist on OVH cloud", flavorName)
+}
+
+func (f *fakeCloud) GetImageByName(projectID, region, imageName string) (*Image, error) {
+	for _, image := range append(append(Images{}, f.Images...), f.Snapshots...) {
+		if image.OS == "linux" && image.Region == region && (image.ID == imageName || image.Name == imageName) {
+			return &image, nil
+		}
+	}
+	return nil, &notFoundError{fmt.Sprintf("Image '%s' does not exist on OVH cloud", imageName)}
+}
+
+// Networks
+
+func (f *fakeCloud) GetPublicNetworkID(projectID string) (string, error) {
+	return f.PublicNetworkID, f.checkProject(projectID)
+}
+
+func (f *fakeCloud) GetPrivateNetworkByName(projectID, networkName string) (*Network, error) {
+	for _, network := range f.Networks {
+		if network.Name == networkName || fmt.Sprintf("%d", network.VlanID) == networkName {
+			found := network.Network
+			return &found, nil
+		}
+	}
+	return nil, &notFoundError{fmt.Sprintf("Invalid private network %s", networkName)}
+}
+
+func (f *fakeCloud) CreatePrivateNetwork(projectID, name string, vlanID int, region string) (*Network, error) {
+	if err := f.call("CreatePrivateNetwork", name); err != nil {
+		return nil, err
+	}
+	if vlanID == 0 {
+		vlanID = 100 + len(f.Networks)
+	}
+	network := &fakeNetwork{
+		Network: Network{ID: f.newID("network"), Name: name, Type: "private", VlanID: vlanID, Status: "BUILDING"},
+		Pending: []string{"ACTIVE"},
+	}
+	f.Networks[network.ID] = network
+	created := network.Network
+	f.settle(&network.Status, &network.Pending)
+	return &created, nil
+}
+
+func (f *fakeCloud) GetPrivateNetwork(projectID, networkID string) (*Network, error) {
+	network, ok := f.Networks[networkID]
+	if !ok {
+		return nil, notFound("network %s does not exist", networkID)
+	}
+	found := network.Network
+	advance(&network.Status, &network.Pending)
+	return &found, nil
+}
+
+func (f *fakeCloud) DeletePrivateNetwork(projectID, networkID string) error {
+	if err := f.call("DeletePrivateNetwork", networkID); err != nil {
+		return err
+	}
+	if _, ok := f.Networks[networkID]; !ok {
+		return nil
+	}
+	for _, instance := range f.Instances {
+		for _, network := range instance.NetworkParams {
+			if network.ID == networkID {
+				return &ovh.APIError{Code: 409, Message: fmt.Sprintf("network %s has ports in use by instance %s", networkID, instance.ID)}
+			}
+		}
+	}
+	delete(f.Networks, networkID)
+	return nil
+}
+
+func (f *fakeCloud) CreateSubnet(projectID, networkID, region, cidr, dhcpStart, dhcpEnd string) (*Subnet, error) {
+	if err := f.call("CreateSubnet", networkID, cidr); err != nil {
+		return nil, err
+	}
+	network, ok := f.Networks[networkID]
+	if !ok {
+		return nil, notFound("network %s does not exist", networkID)
+	}
+	subnet := Subnet{ID: f.newID("subnet"), CIDR: cidr}
+	network.Subnets = append(network.Subnets, subnet)
+	return &subnet, nil
+}
+
+func (f *fakeCloud) GetSubnets(projectID, networkID string) (Subnets, error) {
+	network, ok := f.Networks[networkID]
+	if !ok {
+		return nil, notFound("network %s does not exist", networkID)
+	}
+	return network.Subnets, nil
+}
+
+// SSH keys
+
+func (f *fakeCloud) GetSshkeyByName(projectID, region, sshKeyName string) (*Sshkey, error) {
+	for _, key := range f.Sshkeys {
+		if key.Name == sshKeyName {
+			found := *key
+			return &found, nil
+		}
+	}
+	return nil, fmt.Errorf("SSH key '%s' does not exist on OVH cloud", sshKeyName)
+}
+
+func (f *fakeCloud) CreateSshkey(projectID, name, pubkey string) (*Sshkey, error) {
+	if err := f.call("CreateSshkey", name); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(pubkey, "ssh-") {
+		return nil, badRequest("invalid public key")
+	}
+	key := &Sshkey{ID: f.newID("key"), Name: name, PublicKey: pubkey}
+	f.Sshkeys[key.ID] = key
+	created := *key
+	return &created, nil
+}
+
+func (f *fakeCloud) DeleteSshkey(projectID, sshkeyID string) error {
+	if err := f.call("DeleteSshkey", sshkeyID); err != nil {
+		return err
+	}
+	if _, ok := f.Sshkeys[sshkeyID]; !ok {
+		return notFound("key %s does not exist", sshkeyID)
+	}
+	delete(f.Sshkeys, sshkeyID)
+	return nil
+}
+
+// Instances
+
+func (f *fakeCloud) CreateInstance(projectID, name, pubkeyID, flavorID, imageID, region string, networks NetworkParams, monthlyBilling bool, userData string) (*Instance, error) {
+	if err := f.call("CreateInstance", name); err != nil {
+		return nil, err
+	}
+	if err := f.checkProject(projectID); err != nil {
+		return nil, err
+	}
+	if _, ok := f.Sshkeys[pubkeyID]; !ok {
+		return nil, badRequest("ssh key %s does not exist", pubkeyID)
+	}
+	flavor, err := f.GetFlavorByName(projectID, region, flavorID)
+	if err != nil {
+		return nil, badRequest("flavor %s does not exist", flavorID)
+	}
+	image, err := f.GetImageByName(projectID, region, imageID)
+	if err != nil {
+		return nil, badRequest("image %s does not exist", imageID)
+	}
+
+	id := f.newID("instance")
+	if len(networks) == 0 {
+		networks = NetworkParams{{ID: f.PublicNetworkID}}
+	}
+	var ips IPs
+	for i, network := range networks {
+		if network.ID == f.PublicNetworkID {
+			ips = append(ips,
+				IP{IP: fmt.Sprintf("203.0.113.%d", f.lastID), Type: "public", Version: 4, NetworkID: network.ID},
+				IP{IP: fmt.Sprintf("2001:db8::%d", f.lastID), Type: "public", Version: 6, NetworkID: network.ID},
+			)
+			continue
+		}
+		if _, ok := f.Networks[network.ID]; !ok {
+			return nil, badRequest("network %s does not exist", network.ID)
+		}
+		ip := network.IP
+		if ip == "" {
+			ip = fmt.Sprintf("10.%d.0.%d", i, f.lastID)
+		}
+		ips = append(ips, IP{IP: ip, Type: "private", Version: 4, NetworkID: network.ID})
+	}
+
+	instance := &fakeInstance{
+		Instance: Instance{
+			ID:            id,
+			Name:          name,
+			Status:        "BUILD",
+			Created:       time.Now().UTC().Format(time.RFC3339),
+			Region:        region,
+			NetworkParams: networks,
+			Image:         *image,
+			Flavor:        *flavor,
+			Sshkey:        *f.Sshkeys[pubkeyID],
+			IPAddresses:   ips,
+		},
+		Pending:  []string{"ACTIVE"},
+		UserData: userData,
+	}
+	if monthlyBilling {
+		instance.MonthlyBilling = &MonthlyBilling{Since: instance.Created, Status: "ok"}
+	}
+	f.Instances[id] = instance
+
+	// IP addresses are only known once the instance is active
+	created := instance.Instance
+	created.IPAddresses = nil
+	f.settle(&instance.Status, &instance.Pending)
+	return &created, nil
+}
+
+func (f *fakeCloud) GetInstance(projectID, instanceID string) (*Instance, error) {
+	if err := f.Failures["GetInstance"]; err != nil {
+		return nil, err
+	}
+	instance, ok := f.Instances[instanceID]
+	if !ok {
+		return nil, notFound("instance %s does not exist", instanceID)
+	}
+	if instance.Status == fakeGone {
+		delete(f.Instances, instanceID)
+		return nil, notFound("instance %s does not exist", instanceID)
+	}
+	found := instance.Instance
+	advance(&instance.Status, &instance.Pending)
+	return &found, nil
+}
+
+func (f *fakeCloud) GetInstances(projectID, region string) (instances Instances, err error) {
+	for id, instance := range f.Instances {
+		if instance.Status == fakeGone {
+			delete(f.Instances, id)
+			continue
+		}
+		if region == "" || instance.Region == region {
+			instances = append(instances, instance.Instance)
+			advance(&instance.Status, &instance.Pending)
+		}
+	}
+	return instances, f.checkProject(projectID)
+}
+
+// transition starts an asynchronous operation on an instance in one of from
+func (f *fakeCloud) transition(method, instanceID string, from []string, status string, pending ...string) (*fakeInstance, error) {
+	if err := f.call(method, instanceID); err != nil {
+		return nil, err
+	}
+	instance, ok := f.Instances[instanceID]
+	if !ok || instance.Status == fakeGone {
+		return nil, notFound("instance %s does not exist", instanceID)
+	}
+	if len(from) > 0 {
+		allowed := false
+		for _, s := range from {
+			allowed = allowed || instance.Status == s
+		}
+		if !allowed {
+			return nil, &ovh.APIError{Code: 409, Message: fmt.Sprintf("cannot %s instance %s in status %s", method, instanceID, instance.Status)}
+		}
+	}
+	if status != "" {
+		instance.Status = status
+	}
+	instance.Pending = pending
+	if outcome, ok := f.Outcomes[method]; ok {
+		instance.Pending = append([]string{}, outcome...)
+	}
+	f.settle(&instance.Status, &instance.Pending)
+	return instance, nil
+}
+
+func (f *fakeCloud) RebootInstance(projectID, instanceID string, hard bool) error {
+	status := "REBOOT"
+	if hard {
+		status = "HARD_REBOOT"
+	}
+	_, err := f.transition("RebootInstance", instanceID, nil, status, "ACTIVE")
+	return err
+}
+
+func (f *fakeCloud) StartInstance(projectID, instanceID string) error {
+	_, err := f.transition("StartInstance", instanceID, []string{"SHUTOFF"}, "", "ACTIVE")
+	return err
+}
+
+func (f *fakeCloud) StopInstance(projectID, instanceID string) error {
+	_, err := f.transition("StopInstance", instanceID, []string{"ACTIVE"}, "", "SHUTOFF")
+	return err
+}
+
+func (f *fakeCloud) ShelveInstance(projectID, instanceID string) error {
+	_, err := f.transition("ShelveInstance", instanceID, []string{"ACTIVE", "SHUTOFF"}, "", "SHELVED", "SHELVED_OFFLOADED")
+	return err
+}
+
+func (f *fakeCloud) UnshelveInstance(projectID, instanceID string) error {
+	_, err := f.transition("UnshelveInstance", instanceID, []string{"SHELVED", "SHELVED_OFFLOADED"}, "", "ACTIVE")
+	return err
+}
+
+func (f *fakeCloud) DeleteInstance(projectID, instanceID string) error {
+	_, err := f.transition("DeleteInstance", instanceID, nil, "DELETING", fakeGone)
+	return err
+}
+
+func (f *fakeCloud) ResizeInstance(projectID, instanceID, flavorID string) error {
+	flavor, err := f.GetFlavorByName(projectID, "GRA1", flavorID)
+	if err != nil {
+		return badRequest("flavor %s does not exist", flavorID)
+	}
+	instance, err := f.transition("ResizeInstance", instanceID, []string{"ACTIVE", "SHUTOFF"}, "RESIZE", "VERIFY_RESIZE", "ACTIVE")
+	if err == nil {
+		instance.Flavor = *flavor
+	}
+	return err
+}
+
+func (f *fakeCloud) RescueInstance(projectID, instanceID, imageID string, rescue bool) (*RescueMode, error) {
+	if rescue {
+		_, err := f.transition("RescueInstance", instanceID, []string{"ACTIVE", "SHUTOFF"}, "", "RESCUE")
+		return &RescueMode{AdminPassword: "rescue-password"}, err
+	}
+	_, err := f.transition("RescueInstance", instanceID, []string{"RESCUE"}, "", "ACTIVE")
+	return &RescueMode{}, err
+}
+
+func (f *fakeCloud) ReinstallInstance(projectID, instanceID, imageID string) (*Instance, error) {
+	image, err := f.GetImageByName(projectID, "GRA1", imageID)
+	if err != nil {
+		return nil, badRequest("image %s does not exist", imageID)
+	}
+	instance, err := f.transition("ReinstallInstance", instanceID, []string{"ACTIVE", "SHUTOFF"}, "REBUILD", "ACTIVE")
+	if err != nil {
+		return nil, err
+	}
+	instance.Image = *image
+	found := instance.Instance
+	return &found, nil
+}
+
+func (f *fakeCloud) ActivateMonthlyBilling(projectID, instanceID string) (*Instance, error) {
+	instance, err := f.transition("ActivateMonthlyBilling", instanceID, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	instance.MonthlyBilling = &MonthlyBilling{Since: time.Now().UTC().Format(time.RFC3339), Status: "activationPending"}
+	found := instance.Instance
+	return &found, nil
+}
+
+// Snapshots
+
+func (f *fakeCloud) GetSnapshots(projectID, region string) (snapshots Images, err error) {
+	for _, snapshot := range f.Snapshots {
+		if snapshot.Region == region {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	return snapshots, f.checkProject(projectID)
+}
+
+func (f *fakeCloud) CreateSnapshot(projectID, instanceID, name string) error {
+	if err := f.call("CreateSnapshot", instanceID, name); err != nil {
+		return err
+	}
+	instance, ok := f.Instances[instanceID]
+	if !ok {
+		return notFound("instance %s does not exist", instanceID)
+	}
+	f.Snapshots = append(f.Snapshots, Image{
+		ID:           f.newID("snapshot"),
+		Name:         name,
+		Region:       instance.Region,
+		OS:           "linux",
+		Status:       "active",
+		Visibility:   "private",
+		CreationDate: time.Now().UTC().Format(time.RFC3339),
+	})
+	return nil
+}
+
+func (f *fakeCloud) DeleteSnapshot(projectID, imageID string) error {
+	if err := f.call("DeleteSnapshot", imageID); err != nil {
+		return err
+	}
+	for i, snapshot := range f.Snapshots {
+		if snapshot.ID == imageID {
+			f.Snapshots = append(f.Snapshots[:i], f.Snapshots[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Backups
+
+func (f *fakeCloud) CreateBackupWorkflow(projectID, region, instanceID, name, cron string, rotation int) (*BackupWorkflow, error) {
+	if err := f.call("CreateBackupWorkflow", instanceID, name); err != nil {
+		return nil, err
+	}
+	if _, ok := f.Instances[instanceID]; !ok {
+		return nil, badRequest("instance %s does not exist", instanceID)
+	}
+	workflow := &BackupWorkflow{ID: f.newID("workflow"), Name: name, BackupName: name, InstanceID: instanceID, Cron: cron}
+	f.Workflows[workflow.ID] = workflow
+	created := *workflow
+	return &created, nil
+}
+
+func (f *fakeCloud) DeleteBackupWorkflow(projectID, region, workflowID string) error {
+	if err := f.call("DeleteBackupWorkflow", workflowID); err != nil {
+		return err
+	}
+	delete(f.Workflows, workflowID)
+	return nil
+}
+
+// Volumes
+
+func (f *fakeCloud) CreateVolume(projectID, region, name, volumeType string, size int) (*Volume, error) {
+	if err := f.call("CreateVolume", name); err != nil {
+		return nil, err
+	}
+	volume := &fakeVolume{
+		Volume:  Volume{ID: f.newID("volume"), Name: name, Type: volumeType, Size: size, Region: region, Status: "creating"},
+		Pending: []string{"available"},
+	}
+	f.Volumes[volume.ID] = volume
+	created := volume.Volume
+	f.settle(&volume.Status, &volume.Pending)
+	return &created, nil
+}
+
+func (f *fakeCloud) GetVolume(projectID, volumeID string) (*Volume, error) {
+	volume, ok := f.Volumes[volumeID]
+	if !ok {
+		return nil, notFound("volume %s does not exist", volumeID)
+	}
+	found := volume.Volume
+	found.AttachedTo = append([]string{}, volume.AttachedTo...)
+	advance(&volume.Status, &volume.Pending)
+	return &found, nil
+}
+
+func (f *fakeCloud) AttachVolume(projectID, volumeID, instanceID string) error {
+	if err := f.call("AttachVolume", volumeID, instanceID); err != nil {
+		return err
+	}
+	volume, ok := f.Volumes[volumeID]
+	if !ok {
+		return notFound("volume %s does not exist", volumeID)
+	}
+	if volume.Status != "available" {
+		return &ovh.APIError{Code: 409, Message: fmt.Sprintf("volume %s is %s", volumeID, volume.Status)}
+	}
+	volume.Status = "attaching"
+	volume.Pending = []string{"in-use"}
+	volume.AttachedTo = append(volume.AttachedTo, instanceID)
+	f.settle(&volume.Status, &volume.Pending)
+	return nil
+}
+
+func (f *fakeCloud) DetachVolume(projectID, volumeID, instanceID string) error {
+	if err := f.call("DetachVolume", volumeID, instanceID); err != nil {
+		return err
+	}
+	volume, ok := f.Volumes[volumeID]
+	if !ok {
+		return nil
+	}
+	for i, attached := range volume.AttachedTo {
+		if attached == instanceID {
+			volume.AttachedTo = append(volume.AttachedTo[:i], volume.AttachedTo[i+1:]...)
+			volume.Status = "detaching"
+			volume.Pending = []string{"available"}
+			f.settle(&volume.Status, &volume.Pending)
+			return nil
+		}
+	}
+	return badRequest("volume %s is not attached to %s", volumeID, instanceID)
+}
+
+func (f *fakeCloud) DeleteVolume(projectID, volumeID string) error {
+	if err := f.call("DeleteVolume", volumeID); err != nil {
+		return err
+	}
+	volume, ok := f.Volumes[volumeID]
+	if !ok {
+		return nil
+	}
+	if len(volume.AttachedTo) > 0 {
+		return &ovh.APIError{Code: 409, Message: fmt.Sprintf("volume %s is attached", volumeID)}
+	}
+	delete(f.Volumes, volumeID)
+	return nil
+}
+
+// Failover IPs
+
+func (f *fakeCloud) GetFailoverIPs(projectID string) (FailoverIPs, error) {
+	return append(FailoverIPs{}, f.FailoverIPs...), f.checkProject(projectID)
+}
+
+func (f *fakeCloud) AttachFailoverIP(projectID, failoverIPID, instanceID string) (*FailoverIP, error) {
+	if err := f.call("AttachFailoverIP", failoverIPID, instanceID); err != nil {
+		return nil, err
+	}
+	for i := range f.FailoverIPs {
+		if f.FailoverIPs[i].ID == failoverIPID {
+			f.FailoverIPs[i].RoutedTo = instanceID
+			f.FailoverIPs[i].Status = "ok"
+			ip := f.FailoverIPs[i]
+			return &ip, nil
+		}
+	}
+	return nil, notFound("failover IP %s does not exist", failoverIPID)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"text/template"
+)
+
+// firewallPorts lists the ports opened to the allowed networks: SSH, Docker,
+// legacy Swarm master and Swarm mode
+var firewallPorts = []struct {
+	Proto string
+	Port  int
+}{
+	{"tcp", 22},
+	{"tcp", 2376},
+	{"tcp", 3376},
+	{"tcp", 2377},
+	{"tcp", 7946},
+	{"udp", 7946},
+	{"udp", 4789},
+}
+
+// firewallTemplate is a cloud-init boot hook, run on every boot, restricting
+// incoming traffic on the machine to the Docker and Swarm ports from the
+// allowed networks
+var firewallTemplate = template.Must(template.New("firewall").Parse(`#cloud-boothook
+#!/bin/sh
+# Restrict incoming traffic. Generated by docker-machine-driver-ovh
+for cmd in iptables ip6tables; do
+	$cmd -N OVH-MACHINE 2>/dev/null || $cmd -F OVH-MACHINE
+	$cmd -C INPUT -j OVH-MACHINE 2>/dev/null || $cmd -I INPUT -j OVH-MACHINE
+	$cmd -A OVH-MACHINE -i lo -j ACCEPT
+	$cmd -A OVH-MACHINE -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT
+done
+iptables -A OVH-MACHINE -p icmp -j ACCEPT
+iptables -A OVH-MACHINE -p udp --dport 68 -j ACCEPT
+ip6tables -A OVH-MACHINE -p ipv6-icmp -j ACCEPT
+ip6tables -A OVH-MACHINE -p udp --dport 546 -j ACCEPT
+{{- range $cidr := .CIDRs }}
+{{- range $.Ports }}
+{{ if $cidr.V6 }}ip6tables{{ else }}iptables{{ end }} -A OVH-MACHINE -s {{ $cidr.CIDR }} -p {{ .Proto }} --dport {{ .Port }} -j ACCEPT
+{{- end }}
+{{- end }}
+iptables -A OVH-MACHINE -j DROP
+ip6tables -A OVH-MACHINE -j DROP
+`))
+
+// firewallCIDR is an allowed network, as rendered in the firewall script
+type firewallCIDR struct {
+	CIDR string
+	V6   bool
+}
+
+// getFirewallCIDRs validates the allowed networks
+func (d *Driver) getFirewallCIDRs() (cidrs []firewallCIDR, err error) {
+	for _, allowed := range d.AllowedCIDRs {
+		_, network, err := net.ParseCIDR(allowed)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid allowed network '%s'. Networks are defined as CIDR, for instance 203.0.113.0/24", allowed)
+		}
+		cidrs = append(cidrs, firewallCIDR{
+			CIDR: network.String(),
+			V6:   network.IP.To4() == nil,
+		})
+	}
+	return cidrs, nil
+}
+
+// getFirewallUserData returns the boot hook configuring the machine firewall
+func (d *Driver) getFirewallUserData() (string, error) {
+	cidrs, err := d.getFirewallCIDRs()
+	if err != nil {
+		return "", err
+	}
+
+	var script bytes.Buffer
+	err = firewallTemplate.Execute(&script, map[string]interface{}{
+		"CIDRs": cidrs,
+		"Ports": firewallPorts,
+	})
+	return script.String(), err
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetFirewallUserData(t *testing.T) {
+	tests := []struct {
+		name     string
+		cidrs    []string
+		rules    []string
+		expected string
+	}{
+		{
+			name:  "IPv4",
+			cidrs: []string{"203.0.113.7/24"},
+			rules: []string{
+				"iptables -A OVH-MACHINE -s 203.0.113.0/24 -p tcp --dport 22 -j ACCEPT",
+				"iptables -A OVH-MACHINE -s 203.0.113.0/24 -p tcp --dport 2376 -j ACCEPT",
+				"iptables -A OVH-MACHINE -s 203.0.113.0/24 -p udp --dport 4789 -j ACCEPT",
+			},
+		},
+		{
+			name:  "IPv4 and IPv6",
+			cidrs: []string{"203.0.113.0/24", "2001:db8::/32"},
+			rules: []string{
+				"iptables -A OVH-MACHINE -s 203.0.113.0/24 -p tcp --dport 2377 -j ACCEPT",
+				"ip6tables -A OVH-MACHINE -s 2001:db8::/32 -p tcp --dport 2377 -j ACCEPT",
+				"ip6tables -A OVH-MACHINE -j DROP",
+			},
+		},
+		{
+			name:     "invalid network",
+			cidrs:    []string{"203.0.113.0"},
+			expected: "Invalid allowed network '203.0.113.0'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := &Driver{AllowedCIDRs: test.cidrs}
+			script, err := d.getFirewallUserData()
+			checkError(t, err, test.expected)
+			if err != nil {
+				return
+			}
+			if !strings.HasPrefix(script, "#cloud-boothook\n") {
+				t.Errorf("firewall is not a boot hook:\n%s", script)
+			}
+			for _, rule := range test.rules {
+				if !strings.Contains(script, rule+"\n") {
+					t.Errorf("missing rule %q in:\n%s", rule, script)
+				}
+			}
+		})
+	}
+}
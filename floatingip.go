@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+)
+
+const (
+	// floatingIPScriptPath is where the floating IP configuration is stored on the machine
+	floatingIPScriptPath = "/var/lib/cloud/scripts/per-boot/ovh-floating-ip.sh"
+)
+
+// getFloatingIPScript returns a shell script installing a per-boot script
+// which adds the floating IP to the default interface. OVH routes failover
+// IPs to the instance but does not configure them
+func getFloatingIPScript(ip string) string {
+	family, prefix := "-4", "32"
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		family, prefix = "-6", "128"
+	}
+
+	return fmt.Sprintf(`#!/bin/sh
+# Configure floating IP. Generated by docker-machine-driver-ovh
+mkdir -p %[1]s
+cat > %[2]s << 'FLOATINGIP'
+#!/bin/sh
+iface=$(ip %[3]s route show default | awk '{print $5; exit}')
+ip %[3]s addr add %[4]s/%[5]s dev "$iface" 2>/dev/null || true
+FLOATINGIP
+chmod +x %[2]s
+%[2]s
+`, "/var/lib/cloud/scripts/per-boot", floatingIPScriptPath, family, ip, prefix)
+}
+
+// getUnconfigureFloatingIPScript returns a shell script removing the floating IP from the machine
+func getUnconfigureFloatingIPScript(ip string) string {
+	family, prefix := "-4", "32"
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		family, prefix = "-6", "128"
+	}
+
+	return fmt.Sprintf(`#!/bin/sh
+rm -f %[1]s
+for iface in $(ls /sys/class/net); do
+	ip %[2]s addr del %[3]s/%[4]s dev "$iface" 2>/dev/null || true
+done
+`, floatingIPScriptPath, family, ip, prefix)
+}
+
+// findFloatingIP resolves the floating IP option. With 'auto', the first
+// failover IP of the project not routed to any instance is selected
+func (d *Driver) findFloatingIP(address string) (*FailoverIP, error) {
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := client.GetFailoverIPs(d.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var available []string
+	for _, ip := range ips {
+		ip.IP = strings.Split(ip.IP, "/")[0]
+		if address == "auto" && ip.RoutedTo == "" {
+			return &ip, nil
+		}
+		if ip.IP == address || ip.ID == address {
+			return &ip, nil
+		}
+		available = append(available, ip.IP)
+	}
+
+	if address == "auto" {
+		return nil, &notFoundError{fmt.Sprintf("No unused failover IP in project. To order one, please visit %s", CustomerInterface)}
+	}
+	return nil, &notFoundError{fmt.Sprintf("Failover IP '%s' does not exist on OVH cloud. List of failover IPs include %s", address, strings.Join(available, ", "))}
+}
+
+// attachFloatingIP routes the floating IP to the machine and waits until it is done
+func (d *Driver) attachFloatingIP() error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	log.Info("Attaching floating IP ", d.FloatingIP, "...")
+	_, err = client.AttachFailoverIP(d.ProjectID, d.FloatingIPID, d.InstanceID)
+	if err != nil {
+		return err
+	}
+
+	return mcnutils.WaitForSpecificOrError(func() (bool, error) {
+		ip, err := d.findFloatingIP(d.FloatingIPID)
+		if err != nil {
+			return true, err
+		}
+		log.Debug("Floating IP", map[string]interface{}{
+			"IP":       ip.IP,
+			"RoutedTo": ip.RoutedTo,
+			"State":    ip.Status,
+		})
+		return ip.RoutedTo == d.InstanceID && ip.Status == "ok", nil
+	}, (statusTimeout / 4), statusPollInterval)
+}
+
+// runSSHScript runs a shell script as root on the machine, once it accepts
+// SSH connections
+var runSSHScript = func(d *Driver, script string) error {
+	if err := drivers.WaitForSSH(d); err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte(script))
+	_, err := drivers.RunSSHCommandFromDriver(d, fmt.Sprintf("echo %s | base64 -d | sudo sh", encoded))
+	return err
+}
+
+// MoveFloatingIP moves the floating IP of machine from to machine to. Both
+// machines must belong to the same project
+func MoveFloatingIP(from, to *Driver) error {
+	if from.FloatingIP == "" {
+		return fmt.Errorf("Machine %s has no floating IP", from.MachineName)
+	}
+	if from.ProjectID != to.ProjectID {
+		return fmt.Errorf("Machines %s and %s do not belong to the same project", from.MachineName, to.MachineName)
+	}
+
+	// Route the IP to the new machine
+	to.FloatingIP = from.FloatingIP
+	to.FloatingIPID = from.FloatingIPID
+	to.FloatingIPAuto = from.FloatingIPAuto
+	err := to.attachFloatingIP()
+	if err != nil {
+		return err
+	}
+
+	log.Info("Configuring floating IP on ", to.MachineName, "...")
+	err = runSSHScript(to, getFloatingIPScript(to.FloatingIP))
+	if err != nil {
+		return err
+	}
+	to.IPAddress = to.getPreferredIP()
+
+	// The old machine is now only reachable on its own address
+	floatingIP := from.FloatingIP
+	from.FloatingIP = ""
+	from.FloatingIPID = ""
+	from.FloatingIPAuto = false
+	if ip := from.getPreferredIP(); ip != "" {
+		from.IPAddress = ip
+	}
+
+	// Best effort: the old machine may be stopped or broken
+	log.Info("Removing floating IP from ", from.MachineName, "...")
+	err = runSSHScript(from, getUnconfigureFloatingIPScript(floatingIP))
+	if err != nil {
+		log.Warnf("Could not remove floating IP configuration from %s: %s", from.MachineName, err)
+	}
+
+	return nil
+}
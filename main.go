@@ -1,22 +1,33 @@
 package main
 
 import (
+	"os"
+
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/drivers/plugin"
 )
 
 // Default values for docker-machine-driver-ovh
 const (
-	DefaultSecurityGroup = "default"
-	DefaultProjectName   = "docker-machine"
-	DefaultFlavorName    = "vps-ssd-1"
-	DefaultRegionName    = "GRA1"
-	DefaultImageName     = "Ubuntu 16.04"
-	DefaultSSHUserName   = "ubuntu"
-	DefaultBillingPeriod = "hourly"
+	DefaultProjectName    = "docker-machine"
+	DefaultFlavorName     = "vps-ssd-1"
+	DefaultRegionName     = "GRA1"
+	DefaultImageName      = "Ubuntu 16.04"
+	DefaultSSHUserName    = "ubuntu"
+	RescueSSHUserName     = "root"
+	DefaultBillingPeriod  = "hourly"
+	DefaultStopMode       = "stop"
+	DefaultVolumeType     = "classic"
+	DefaultNetworkCIDR    = "192.168.0.0/24"
+	DefaultBackupRotation = 7
 )
 
 func main() {
+	// Maintenance commands are run directly, docker-machine passes no argument
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+
 	plugin.RegisterDriver(&Driver{
 		BaseDriver: &drivers.BaseDriver{
 			SSHUser: DefaultSSHUserName,
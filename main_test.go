@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/rpc"
+	"github.com/docker/machine/libmachine/state"
+)
+
+// TestPlugin builds the driver and drives it like docker-machine does, over
+// libmachine RPC, against the stand-in OVH API server
+func TestPlugin(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs the driver binary")
+	}
+
+	dir := t.TempDir()
+	build := exec.Command("go", "build", "-o", filepath.Join(dir, "docker-machine-driver-ovh"), ".")
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("Could not build the driver: %s\n%s", err, output)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// The binary polls every few seconds, settle operations at once instead
+	cloud := newFakeCloud()
+	cloud.Settle = true
+	server := newOVHServer(t, cloud)
+
+	raw, err := json.Marshal(drivers.BaseDriver{
+		MachineName: "test-machine",
+		StorePath:   t.TempDir(),
+		SSHUser:     DefaultSSHUserName,
+		SSHPort:     22,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	factory := rpcdriver.NewRPCClientDriverFactory()
+	defer factory.Close()
+	d, err := factory.NewRPCClientDriver("ovh", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := d.DriverName(); name != "ovh" {
+		t.Fatalf("unexpected driver name %q", name)
+	}
+
+	// Like docker-machine, pass every create flag, booleans defaulting to false
+	flags := &rpcdriver.RPCFlags{Values: map[string]interface{}{
+		"swarm-master":    false,
+		"swarm-host":      "",
+		"swarm-discovery": "",
+	}}
+	for _, flag := range d.GetCreateFlags() {
+		flags.Values[flag.String()] = flag.Default()
+		if flags.Values[flag.String()] == nil {
+			flags.Values[flag.String()] = false
+		}
+	}
+	for key, value := range map[string]interface{}{
+		"ovh-endpoint":               server.URL + "/1.0",
+		"ovh-application-key":        testApplicationKey,
+		"ovh-application-secret":     testApplicationSecret,
+		"ovh-consumer-key":           testConsumerKey,
+		"ovh-private-network":        []string{"backend"},
+		"ovh-private-network-create": true,
+		"ovh-volume-size":            10,
+	} {
+		flags.Values[key] = value
+	}
+	checkError(t, d.SetConfigFromFlags(flags), "")
+
+	checkError(t, d.PreCreateCheck(), "")
+	checkError(t, d.Create(), "")
+	if st, err := d.GetState(); st != state.Running || err != nil {
+		t.Errorf("unexpected state %s after create, error %v", st, err)
+	}
+	if url, err := d.GetURL(); url != "tcp://203.0.113.4:2376" || err != nil {
+		t.Errorf("unexpected URL %s, error %v", url, err)
+	}
+	if len(cloud.Instances) != 1 || len(cloud.Networks) != 1 || len(cloud.Volumes) != 1 || len(cloud.Sshkeys) != 1 {
+		t.Errorf("unexpected resources after create: %v", liveResources(cloud))
+	}
+
+	checkError(t, d.Stop(), "")
+	if st, err := d.GetState(); st != state.Stopped || err != nil {
+		t.Errorf("unexpected state %s after stop, error %v", st, err)
+	}
+
+	checkError(t, d.Remove(), "")
+	if resources := liveResources(cloud); len(resources) != 0 {
+		t.Errorf("resources left after remove: %v", resources)
+	}
+}
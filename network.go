@@ -0,0 +1,528 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+)
+
+// PrivateNetwork records a private network attached to the machine and the
+// address the machine got on it
+type PrivateNetwork struct {
+	Name   string
+	ID     string
+	VlanID int
+	IP     string
+
+	// Created is true when the network was created by the driver
+	Created bool
+}
+
+// newPrivateNetwork validates the parameters of a private network to create
+// with the machine. Only one network may be created per machine
+func (d *Driver) newPrivateNetwork(name string) (network PrivateNetwork, err error) {
+	for _, privateNetwork := range d.PrivateNetworks {
+		if privateNetwork.Created {
+			return network, fmt.Errorf("Private networks %s and %s do not exist. Only one private network may be created per machine", privateNetwork.Name, name)
+		}
+	}
+
+	network.Name = name
+	network.Created = true
+	network.VlanID = d.PrivateNetworkVlanID
+	if network.VlanID == 0 {
+		if vlanID, err := strconv.Atoi(name); err == nil {
+			network.VlanID = vlanID
+		}
+	}
+	if network.VlanID < 0 || network.VlanID > 4000 {
+		return network, fmt.Errorf("Invalid vlan number %d. Vlan numbers range from 0 to 4000", network.VlanID)
+	}
+
+	d.PrivateNetworkDHCPStart, d.PrivateNetworkDHCPEnd, err = getDHCPRange(d.PrivateNetworkCIDR, d.PrivateNetworkDHCPStart, d.PrivateNetworkDHCPEnd)
+	return network, err
+}
+
+// getDHCPRange validates a DHCP range against an IPv4 subnet. Missing bounds
+// default to the second and last usable addresses of the subnet
+func getDHCPRange(cidr, start, end string) (string, string, error) {
+	_, subnet, err := net.ParseCIDR(cidr)
+	if err != nil || subnet.IP.To4() == nil {
+		return "", "", fmt.Errorf("Invalid private network subnet '%s'. Please use an IPv4 subnet such as %s", cidr, DefaultNetworkCIDR)
+	}
+
+	ones, bits := subnet.Mask.Size()
+	if bits-ones < 2 {
+		return "", "", fmt.Errorf("Private network subnet '%s' is too small", cidr)
+	}
+	first := ipToInt(subnet.IP.To4())
+	last := first | (1<<uint(bits-ones) - 1)
+
+	if start == "" {
+		start = intToIP(first + 2).String()
+	}
+	if end == "" {
+		end = intToIP(last - 1).String()
+	}
+
+	for _, bound := range []string{start, end} {
+		ip := net.ParseIP(bound)
+		if ip == nil || !subnet.Contains(ip) {
+			return "", "", fmt.Errorf("Invalid DHCP address '%s'. It must belong to private network subnet %s", bound, cidr)
+		}
+	}
+	if ipToInt(net.ParseIP(start).To4()) > ipToInt(net.ParseIP(end).To4()) {
+		return "", "", fmt.Errorf("Invalid DHCP range %s - %s", start, end)
+	}
+
+	return start, end, nil
+}
+
+// ipToInt converts an IPv4 address to an integer
+func ipToInt(ip net.IP) uint32 {
+	return uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3])
+}
+
+// intToIP converts an integer to an IPv4 address
+func intToIP(n uint32) net.IP {
+	return net.IPv4(byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+// updateNetworkIDs lists the networks to attach to the instance, in NIC order
+func (d *Driver) updateNetworkIDs() {
+	d.NetworkIDs = nil
+	for _, network := range d.PrivateNetworks {
+		if network.ID != "" {
+			d.NetworkIDs = append(d.NetworkIDs, network.ID)
+		}
+	}
+	if d.PublicNetworkID != "" {
+		d.NetworkIDs = append(d.NetworkIDs, d.PublicNetworkID)
+	}
+}
+
+// getNetworkParams returns the networks to attach to the instance, with the
+// static private IP requested on the first private network
+func (d *Driver) getNetworkParams() (networks NetworkParams) {
+	for _, networkID := range d.NetworkIDs {
+		network := NetworkParam{ID: networkID}
+		if d.PrivateIP != "" && len(d.PrivateNetworks) > 0 && networkID == d.PrivateNetworks[0].ID {
+			network.IP = d.PrivateIP
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// checkPrivateIP makes sure the static private IP belongs to a subnet of the
+// first private network and is not used by another instance of the project
+func (d *Driver) checkPrivateIP() error {
+	if len(d.PrivateNetworks) == 0 {
+		return fmt.Errorf("A static private IP needs a private network. Please use the '--ovh-private-network' option")
+	}
+	network := d.PrivateNetworks[0]
+
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(d.PrivateIP)
+	if ip == nil {
+		return fmt.Errorf("Invalid private IP '%s'", d.PrivateIP)
+	}
+
+	// Find the subnets of the network
+	var cidrs []string
+	if network.Created {
+		cidrs = append(cidrs, d.PrivateNetworkCIDR)
+	} else {
+		subnets, err := client.GetSubnets(d.ProjectID, network.ID)
+		if err != nil {
+			return err
+		}
+		for _, subnet := range subnets {
+			cidrs = append(cidrs, subnet.CIDR)
+		}
+	}
+
+	inSubnet := false
+	for _, cidr := range cidrs {
+		_, subnet, err := net.ParseCIDR(cidr)
+		if err == nil && subnet.Contains(ip) {
+			inSubnet = true
+			break
+		}
+	}
+	if !inSubnet {
+		return fmt.Errorf("Private IP %s does not belong to private network %s. Valid subnets include %v", d.PrivateIP, network.Name, cidrs)
+	}
+
+	// A newly created network has no instance yet
+	if network.Created {
+		d.PrivateNetworks[0].IP = d.PrivateIP
+		return nil
+	}
+
+	instances, err := client.GetInstances(d.ProjectID, "")
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		for _, instanceIP := range instance.IPAddresses {
+			if instanceIP.NetworkID == network.ID && net.ParseIP(instanceIP.IP).Equal(ip) {
+				return fmt.Errorf("Private IP %s is already used by instance %s", d.PrivateIP, instance.Name)
+			}
+		}
+	}
+
+	d.PrivateNetworks[0].IP = d.PrivateIP
+	return nil
+}
+
+// waitForPrivateNetworkStatus waits until a private network reaches status
+func (d *Driver) waitForPrivateNetworkStatus(networkID, status string) (network *Network, err error) {
+	return network, mcnutils.WaitForSpecificOrError(func() (bool, error) {
+		network, err = d.client.GetPrivateNetwork(d.ProjectID, networkID)
+		if err != nil {
+			return true, err
+		}
+		log.Debug("Private network", map[string]interface{}{
+			"NetworkID": networkID,
+			"State":     network.Status,
+		})
+
+		return network.Status == status, nil
+	}, (statusTimeout / 4), statusPollInterval)
+}
+
+// waitForPrivateNetworkRelease waits until no instance uses a private network
+func (d *Driver) waitForPrivateNetworkRelease(networkID string) error {
+	return mcnutils.WaitForSpecificOrError(func() (bool, error) {
+		instances, err := d.client.GetInstances(d.ProjectID, d.RegionName)
+		if err != nil {
+			return true, err
+		}
+
+		for _, instance := range instances {
+			for _, ip := range instance.IPAddresses {
+				if ip.NetworkID == networkID {
+					log.Debug("Private network", map[string]interface{}{
+						"NetworkID": networkID,
+						"UsedBy":    instance.ID,
+						"State":     instance.Status,
+					})
+					return false, nil
+				}
+			}
+		}
+		return true, nil
+	}, (statusTimeout / 4), statusPollInterval)
+}
+
+// createPrivateNetworks creates the missing private networks with their
+// subnet. Created networks are registered in created for rollback
+func (d *Driver) createPrivateNetworks(created *rollback) error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	for i, privateNetwork := range d.PrivateNetworks {
+		if !privateNetwork.Created || privateNetwork.ID != "" {
+			continue
+		}
+
+		log.Info("Creating OVH private network ", privateNetwork.Name, "...")
+		network, err := client.CreatePrivateNetwork(d.ProjectID, privateNetwork.Name, privateNetwork.VlanID, d.RegionName)
+		if err != nil {
+			return err
+		}
+
+		networkID := network.ID
+		d.PrivateNetworks[i].ID = networkID
+		created.add("private network "+privateNetwork.Name, func() error {
+			// Instance deletion is asynchronous, its port must be gone first
+			err := d.waitForPrivateNetworkRelease(networkID)
+			if err != nil {
+				return err
+			}
+			return client.DeletePrivateNetwork(d.ProjectID, networkID)
+		})
+
+		network, err = d.waitForPrivateNetworkStatus(networkID, "ACTIVE")
+		if err != nil {
+			return err
+		}
+		d.PrivateNetworks[i].VlanID = network.VlanID
+
+		log.Debug("Creating OVH private network subnet...", map[string]interface{}{
+			"NetworkID": networkID,
+			"CIDR":      d.PrivateNetworkCIDR,
+		})
+		_, err = client.CreateSubnet(d.ProjectID, networkID, d.RegionName, d.PrivateNetworkCIDR, d.PrivateNetworkDHCPStart, d.PrivateNetworkDHCPEnd)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removePrivateNetworks deletes the private networks created by the driver,
+// unless other instances still use them. The instance must be deleted first
+func (d *Driver) removePrivateNetworks() error {
+	var owned []PrivateNetwork
+	for _, network := range d.PrivateNetworks {
+		if network.Created && network.ID != "" {
+			owned = append(owned, network)
+		}
+	}
+	if len(owned) == 0 {
+		return nil
+	}
+
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	// Wait until the instance is gone and releases its ports
+	if d.InstanceID != "" {
+		err = mcnutils.WaitForSpecificOrError(func() (bool, error) {
+			_, err := client.GetInstance(d.ProjectID, d.InstanceID)
+			if IsNotFound(err) {
+				return true, nil
+			}
+			return false, err
+		}, (statusTimeout / 4), statusPollInterval)
+		if err != nil {
+			return err
+		}
+	}
+
+	instances, err := client.GetInstances(d.ProjectID, d.RegionName)
+	if err != nil {
+		return err
+	}
+
+	for _, network := range owned {
+		var users []string
+		for _, instance := range instances {
+			for _, ip := range instance.IPAddresses {
+				if instance.ID != d.InstanceID && ip.NetworkID == network.ID {
+					users = append(users, instance.Name)
+					break
+				}
+			}
+		}
+
+		if len(users) > 0 {
+			log.Infof("Keeping private network %s, still used by %v", network.Name, users)
+			continue
+		}
+
+		log.Debug("deleting private network...", map[string]interface{}{"NetworkID": network.ID})
+		err = client.DeletePrivateNetwork(d.ProjectID, network.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updatePrivateIPAddresses saves the address of instance on each private network
+func (d *Driver) updatePrivateIPAddresses(instance *Instance) {
+	for i, network := range d.PrivateNetworks {
+		for _, ip := range instance.IPAddresses {
+			if ip.Type == "private" && ip.NetworkID == network.ID {
+				d.PrivateNetworks[i].IP = ip.IP
+				log.Debug("Private IP address found", map[string]interface{}{
+					"MachineID": d.InstanceID,
+					"Network":   network.Name,
+					"IP":        ip.IP,
+				})
+				break
+			}
+		}
+	}
+}
+
+// getIPPreference returns the kind of address docker-machine should use
+func (d *Driver) getIPPreference() string {
+	if d.IPPreference != "" {
+		return d.IPPreference
+	}
+	if d.NoPublicNetwork {
+		return "private"
+	}
+	return "public-v4"
+}
+
+// ipv6RegionService is the service listed by the regions providing IPv6
+const ipv6RegionService = "ipv6"
+
+// checkIPv6 validates the IPv6 options and checks that the region provides
+// IPv6, so that no instance is created without it
+func (d *Driver) checkIPv6() error {
+	if d.NoPublicNetwork {
+		return fmt.Errorf("IPv6 needs a public network. Please remove '--ovh-no-public-network'")
+	}
+
+	if d.IPv6DockerCIDR != "" {
+		_, subnet, err := net.ParseCIDR(d.IPv6DockerCIDR)
+		if err != nil || subnet.IP.To4() != nil {
+			return fmt.Errorf("Invalid Docker IPv6 subnet '%s'. Please use an IPv6 subnet such as 2001:db8:1::/80", d.IPv6DockerCIDR)
+		}
+		d.IPv6DockerCIDR = subnet.String()
+		d.IPv6Docker = true
+	}
+
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+	region, err := client.GetRegion(d.ProjectID, d.RegionName)
+	if err != nil {
+		return err
+	}
+	available := false
+	for _, service := range region.Services {
+		available = available || (service.Name == ipv6RegionService && service.Status == "UP")
+	}
+	if !available {
+		return fmt.Errorf("IPv6 is not available in region %s. Please select another region or remove the IPv6 options", d.RegionName)
+	}
+
+	d.IPv6 = true
+	return nil
+}
+
+// getDockerIPv6CIDR returns the subnet used by Docker containers, a /80 of
+// the /64 block of the instance IPv6 address which does not hold the address
+func getDockerIPv6CIDR(address string) (string, error) {
+	ip := net.ParseIP(address)
+	if ip == nil || ip.To4() != nil {
+		return "", fmt.Errorf("Invalid instance IPv6 address '%s'", address)
+	}
+
+	subnet := ip.Mask(net.CIDRMask(64, 128))
+	subnet[8], subnet[9] = ^ip[8], ^ip[9]
+	return (&net.IPNet{IP: subnet, Mask: net.CIDRMask(80, 128)}).String(), nil
+}
+
+// enableDockerIPv6 enables IPv6 in the Docker daemon configuration of the
+// machine, using a subnet of its IPv6 block unless one was given
+func (d *Driver) enableDockerIPv6() error {
+	if d.IPv6DockerCIDR == "" {
+		cidr, err := getDockerIPv6CIDR(d.IPv6Address)
+		if err != nil {
+			return err
+		}
+		d.IPv6DockerCIDR = cidr
+	}
+
+	log.Info("Enabling IPv6 in Docker with subnet ", d.IPv6DockerCIDR, "...")
+	return runSSHScript(d, getDockerIPv6Script(d.IPv6DockerCIDR))
+}
+
+// getDockerIPv6Script returns a shell script enabling IPv6 in the Docker
+// daemon configuration, unless the image ships its own configuration
+func getDockerIPv6Script(cidr string) string {
+	return fmt.Sprintf(`#!/bin/sh
+# Enable IPv6 in Docker. Generated by docker-machine-driver-ovh
+mkdir -p /etc/docker
+[ -e /etc/docker/daemon.json ] || cat > /etc/docker/daemon.json << 'DOCKER'
+{
+  "ipv6": true,
+  "fixed-cidr-v6": "%s"
+}
+DOCKER
+`, cidr)
+}
+
+// getIPVersion returns the IP version of ip, guessing it from the address if
+// the API did not report it
+func getIPVersion(ip IP) int {
+	if ip.Version != 0 {
+		return ip.Version
+	}
+	if parsed := net.ParseIP(ip.IP); parsed != nil && parsed.To4() == nil {
+		return 6
+	}
+	return 4
+}
+
+// getPreferredIP returns the known address of the machine matching the IP
+// preference, or an empty string
+func (d *Driver) getPreferredIP() string {
+	// The floating IP takes over the public address of its own family only
+	floatingIPVersion := getIPVersion(IP{IP: d.FloatingIP})
+
+	switch d.getIPPreference() {
+	case "private":
+		for _, network := range d.PrivateNetworks {
+			if network.IP != "" {
+				return network.IP
+			}
+		}
+		for _, ip := range d.InstanceIPs {
+			if ip.Type == "private" {
+				return ip.IP
+			}
+		}
+	case "public-v6":
+		if d.FloatingIP != "" && floatingIPVersion == 6 {
+			return d.FloatingIP
+		}
+		for _, ip := range d.InstanceIPs {
+			if ip.Type == "public" && getIPVersion(ip) == 6 {
+				return ip.IP
+			}
+		}
+	default:
+		if d.FloatingIP != "" && floatingIPVersion == 4 {
+			return d.FloatingIP
+		}
+		for _, ip := range d.InstanceIPs {
+			if ip.Type == "public" && getIPVersion(ip) == 4 {
+				return ip.IP
+			}
+		}
+	}
+	return ""
+}
+
+// vrackConfigScript configures every network interface left without an IPv4
+// address on first boot, which are the vRack interfaces. It supports netplan
+// (recent Ubuntu), ifupdown (Debian, older Ubuntu) and systemd-networkd
+// (CoreOS, which already uses DHCP on every interface by default)
+const vrackConfigScript = `#!/bin/sh
+# Configure vRack interfaces. Generated by docker-machine-driver-ovh
+for path in /sys/class/net/*; do
+	iface=$(basename "$path")
+	[ -e "$path/device" ] || continue
+	ip -4 addr show dev "$iface" | grep -q inet && continue
+
+	if [ -d /etc/netplan ]; then
+		cat > "/etc/netplan/99-vrack-$iface.yaml" << VRACK
+network:
+  version: 2
+  ethernets:
+    $iface:
+      dhcp4: true
+VRACK
+		netplan apply
+	elif [ -d /etc/network/interfaces.d ]; then
+		printf 'auto %s\niface %s inet dhcp\n' "$iface" "$iface" > "/etc/network/interfaces.d/99-vrack-$iface.cfg"
+		ifup "$iface"
+	elif [ -d /etc/systemd/network ]; then
+		printf '[Match]\nName=%s\n\n[Network]\nDHCP=ipv4\n' "$iface" > "/etc/systemd/network/99-vrack-$iface.network"
+		systemctl restart systemd-networkd
+	fi
+done
+`
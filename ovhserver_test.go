@@ -0,0 +1,984 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/state"
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// Credentials accepted by the stand-in server
+const (
+	testApplicationKey    = "test-application-key"
+	testApplicationSecret = "test-application-secret"
+	testConsumerKey       = "test-consumer-key"
+)
+
+// ovhServer is an httptest stand-in for the OVH API. It serves the /cloud
+// routes used by the driver with the JSON shapes of the OVH schema, backed by
+// a fakeCloud. Requests must be signed like go-ovh does and request bodies
+// may only hold the exact field names OVH knows about
+type ovhServer struct {
+	*httptest.Server
+	t      *testing.T
+	cloud  *fakeCloud
+	routes []ovhRoute
+	mutex  sync.Mutex
+}
+
+// ovhRoute serves an API call. params are the submatches of pattern, the
+// first one being the project id
+type ovhRoute struct {
+	method  string
+	pattern *regexp.Regexp
+	handler func(r *ovhRequest, params []string) (interface{}, error)
+}
+
+// ovhRequest is an authenticated API call
+type ovhRequest struct {
+	*http.Request
+	body []byte
+}
+
+// newOVHServer starts a stand-in server for cloud, stopped with the test.
+// Its API endpoint is server.URL + "/1.0"
+func newOVHServer(t *testing.T, cloud *fakeCloud) *ovhServer {
+	s := &ovhServer{t: t, cloud: cloud}
+	s.routes = s.cloudRoutes()
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *ovhServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/1.0")
+	if r.Method == "GET" && path == "/auth/time" {
+		s.reply(w, time.Now().Unix(), nil)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.reply(w, nil, err)
+		return
+	}
+	if err := s.checkSignature(r, body); err != nil {
+		s.reply(w, nil, err)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, route := range s.routes {
+		params := route.pattern.FindStringSubmatch(path)
+		if route.method == r.Method && params != nil {
+			result, err := route.handler(&ovhRequest{r, body}, params[1:])
+			s.reply(w, result, err)
+			return
+		}
+	}
+	s.t.Errorf("Unexpected OVH API call %s %s", r.Method, r.URL)
+	s.reply(w, nil, notFound("Got an invalid (or empty) URL"))
+}
+
+// checkSignature verifies the X-Ovh-* headers of an authenticated call
+func (s *ovhServer) checkSignature(r *http.Request, body []byte) error {
+	if r.Header.Get("X-Ovh-Application") != testApplicationKey {
+		return &ovh.APIError{Code: 403, Message: "This application key is invalid"}
+	}
+	if r.Header.Get("X-Ovh-Consumer") != testConsumerKey {
+		return &ovh.APIError{Code: 403, Message: "This credential does not exist"}
+	}
+
+	timestamp := r.Header.Get("X-Ovh-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(seconds, 0)) > time.Minute || time.Until(time.Unix(seconds, 0)) > time.Minute {
+		return badRequest("Invalid timestamp %q", timestamp)
+	}
+
+	h := sha1.New()
+	fmt.Fprintf(h, "%s+%s+%s+%s+%s+%s", testApplicationSecret, testConsumerKey, r.Method, s.URL+r.URL.RequestURI(), body, timestamp)
+	if r.Header.Get("X-Ovh-Signature") != fmt.Sprintf("$1$%x", h.Sum(nil)) {
+		return badRequest("Invalid signature")
+	}
+	return nil
+}
+
+// reply writes result as JSON, or err as an OVH error message
+func (s *ovhServer) reply(w http.ResponseWriter, result interface{}, err error) {
+	code := http.StatusOK
+	if err != nil {
+		code = http.StatusInternalServerError
+		if apierror, ok := err.(*ovh.APIError); ok {
+			code = apierror.Code
+			err = fmt.Errorf("%s", apierror.Message)
+		}
+		result = map[string]string{"message": err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(result)
+}
+
+// decode unmarshals the request body into v. Like OVH, it rejects unknown
+// fields, which must match the json tags of v exactly, and missing required
+// fields
+func (r *ovhRequest) decode(v interface{}, required ...string) error {
+	if err := checkFields(r.body, reflect.TypeOf(v).Elem()); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(r.body, &fields); err != nil {
+		return badRequest("Invalid JSON body: %s", err)
+	}
+	for _, field := range required {
+		if _, ok := fields[field]; !ok {
+			return badRequest("[%s] Property is mandatory", field)
+		}
+	}
+	return json.Unmarshal(r.body, v)
+}
+
+// checkFields rejects the JSON object fields of data which are not json
+// tags of t, recursively. encoding/json matches field names case
+// insensitively, OVH does not
+func checkFields(data []byte, t reflect.Type) error {
+	switch t.Kind() {
+	case reflect.Slice:
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return badRequest("Invalid JSON body: %s", err)
+		}
+		for _, item := range items {
+			if err := checkFields(item, t.Elem()); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return badRequest("Invalid JSON body: %s", err)
+		}
+	next:
+		for name, value := range fields {
+			for i := 0; i < t.NumField(); i++ {
+				if strings.Split(t.Field(i).Tag.Get("json"), ",")[0] == name {
+					if err := checkFields(value, t.Field(i).Type); err != nil {
+						return err
+					}
+					continue next
+				}
+			}
+			return badRequest("Invalid property %s", name)
+		}
+	}
+	return nil
+}
+
+// Request bodies, with the field names of the OVH API schema
+
+type wireNetworkCreation struct {
+	Name    string   `json:"name"`
+	Regions []string `json:"regions"`
+	VlanID  int      `json:"vlanId"`
+}
+
+type wireSubnetCreation struct {
+	DHCP      bool   `json:"dhcp"`
+	End       string `json:"end"`
+	Network   string `json:"network"`
+	NoGateway bool   `json:"noGateway"`
+	Region    string `json:"region"`
+	Start     string `json:"start"`
+}
+
+type wireSshkeyCreation struct {
+	Name      string `json:"name"`
+	PublicKey string `json:"publicKey"`
+	Region    string `json:"region"`
+}
+
+type wireInstanceNetwork struct {
+	IP        string `json:"ip"`
+	NetworkID string `json:"networkId"`
+}
+
+type wireInstanceCreation struct {
+	FlavorID       string                `json:"flavorId"`
+	GroupID        string                `json:"groupId"`
+	ImageID        string                `json:"imageId"`
+	MonthlyBilling bool                  `json:"monthlyBilling"`
+	Name           string                `json:"name"`
+	Networks       []wireInstanceNetwork `json:"networks"`
+	Region         string                `json:"region"`
+	SshKeyID       string                `json:"sshKeyId"`
+	UserData       string                `json:"userData"`
+	VolumeID       string                `json:"volumeId"`
+}
+
+type wireReboot struct {
+	Type string `json:"type"`
+}
+
+type wireSnapshotCreation struct {
+	SnapshotName string `json:"snapshotName"`
+}
+
+type wireResize struct {
+	FlavorID string `json:"flavorId"`
+}
+
+type wireRescueMode struct {
+	ImageID string `json:"imageId"`
+	Rescue  bool   `json:"rescue"`
+}
+
+type wireReinstall struct {
+	ImageID string `json:"imageId"`
+}
+
+type wireVolumeCreation struct {
+	Description string `json:"description"`
+	ImageID     string `json:"imageId"`
+	Name        string `json:"name"`
+	Region      string `json:"region"`
+	Size        int    `json:"size"`
+	SnapshotID  string `json:"snapshotId"`
+	Type        string `json:"type"`
+}
+
+type wireInstanceID struct {
+	InstanceID string `json:"instanceId"`
+}
+
+type wireBackupCreation struct {
+	Cron              string `json:"cron"`
+	InstanceID        string `json:"instanceId"`
+	MaxExecutionCount *int   `json:"maxExecutionCount"`
+	Name              string `json:"name"`
+	Rotation          int    `json:"rotation"`
+}
+
+// Responses, with the field names of the OVH API schema
+
+type wireProject struct {
+	Access       string  `json:"access"`
+	CreationDate string  `json:"creationDate"`
+	Description  string  `json:"description"`
+	Expiration   *string `json:"expiration"`
+	OrderID      *int    `json:"orderId"`
+	PlanCode     string  `json:"planCode"`
+	ProjectID    string  `json:"project_id"`
+	Status       string  `json:"status"`
+	Unleash      bool    `json:"unleash"`
+}
+
+type wireRegionService struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+type wireRegion struct {
+	ContinentCode      string              `json:"continentCode"`
+	DatacenterLocation string              `json:"datacenterLocation"`
+	Name               string              `json:"name"`
+	Services           []wireRegionService `json:"services"`
+	Status             string              `json:"status"`
+}
+
+type wireFlavor struct {
+	Available         bool   `json:"available"`
+	Disk              int    `json:"disk"`
+	ID                string `json:"id"`
+	InboundBandwidth  int    `json:"inboundBandwidth"`
+	Name              string `json:"name"`
+	OSType            string `json:"osType"`
+	OutboundBandwidth int    `json:"outboundBandwidth"`
+	RAM               int    `json:"ram"`
+	Region            string `json:"region"`
+	Type              string `json:"type"`
+	Vcpus             int    `json:"vcpus"`
+}
+
+type wireImage struct {
+	CreationDate string  `json:"creationDate"`
+	ID           string  `json:"id"`
+	MinDisk      int     `json:"minDisk"`
+	MinRAM       int     `json:"minRam"`
+	Name         string  `json:"name"`
+	PlanCode     *string `json:"planCode"`
+	Region       string  `json:"region"`
+	Size         float64 `json:"size"`
+	Status       string  `json:"status"`
+	Type         string  `json:"type"`
+	User         string  `json:"user"`
+	Visibility   string  `json:"visibility"`
+}
+
+type wireNetworkRegion struct {
+	OpenstackID string `json:"openstackId"`
+	Region      string `json:"region"`
+	Status      string `json:"status"`
+}
+
+type wireNetwork struct {
+	ID      string              `json:"id"`
+	Name    string              `json:"name"`
+	Regions []wireNetworkRegion `json:"regions"`
+	Status  string              `json:"status"`
+	Type    string              `json:"type"`
+	VlanID  int                 `json:"vlanId"`
+}
+
+type wireIPPool struct {
+	DHCP    bool   `json:"dhcp"`
+	End     string `json:"end"`
+	Network string `json:"network"`
+	Region  string `json:"region"`
+	Start   string `json:"start"`
+}
+
+type wireSubnet struct {
+	CIDR      string       `json:"cidr"`
+	GatewayIP string       `json:"gatewayIp"`
+	ID        string       `json:"id"`
+	IPPools   []wireIPPool `json:"ipPools"`
+}
+
+type wireSshkey struct {
+	FingerPrint string   `json:"fingerPrint"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	PublicKey   string   `json:"publicKey"`
+	Regions     []string `json:"regions"`
+}
+
+type wireIPAddress struct {
+	GatewayIP string `json:"gatewayIp"`
+	IP        string `json:"ip"`
+	NetworkID string `json:"networkId"`
+	Type      string `json:"type"`
+	Version   int    `json:"version"`
+}
+
+type wireMonthlyBilling struct {
+	Since  string `json:"since"`
+	Status string `json:"status"`
+}
+
+type wireInstance struct {
+	Created        string              `json:"created"`
+	FlavorID       string              `json:"flavorId"`
+	ID             string              `json:"id"`
+	ImageID        string              `json:"imageId"`
+	IPAddresses    []wireIPAddress     `json:"ipAddresses"`
+	MonthlyBilling *wireMonthlyBilling `json:"monthlyBilling"`
+	Name           string              `json:"name"`
+	PlanCode       string              `json:"planCode"`
+	Region         string              `json:"region"`
+	SshKeyID       string              `json:"sshKeyId"`
+	Status         string              `json:"status"`
+}
+
+// wireInstanceDetail is a single instance, with its related objects
+type wireInstanceDetail struct {
+	Created        string              `json:"created"`
+	Flavor         wireFlavor          `json:"flavor"`
+	ID             string              `json:"id"`
+	Image          wireImage           `json:"image"`
+	IPAddresses    []wireIPAddress     `json:"ipAddresses"`
+	MonthlyBilling *wireMonthlyBilling `json:"monthlyBilling"`
+	Name           string              `json:"name"`
+	PlanCode       string              `json:"planCode"`
+	Region         string              `json:"region"`
+	SshKey         *wireSshkey         `json:"sshKey"`
+	Status         string              `json:"status"`
+}
+
+type wireVolume struct {
+	AttachedTo   []string `json:"attachedTo"`
+	Bootable     bool     `json:"bootable"`
+	CreationDate string   `json:"creationDate"`
+	Description  string   `json:"description"`
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Region       string   `json:"region"`
+	Size         int      `json:"size"`
+	Status       string   `json:"status"`
+	Type         string   `json:"type"`
+}
+
+type wireRescuePassword struct {
+	AdminPassword *string `json:"adminPassword"`
+}
+
+type wireFailoverIP struct {
+	Block         string `json:"block"`
+	ContinentCode string `json:"continentCode"`
+	Geoloc        string `json:"geoloc"`
+	ID            string `json:"id"`
+	IP            string `json:"ip"`
+	Progress      int    `json:"progress"`
+	RoutedTo      string `json:"routedTo"`
+	Status        string `json:"status"`
+	SubType       string `json:"subType"`
+}
+
+type wireQuota struct {
+	Instance struct {
+		MaxCores      int `json:"maxCores"`
+		MaxInstances  int `json:"maxInstances"`
+		MaxRAM        int `json:"maxRam"`
+		UsedCores     int `json:"usedCores"`
+		UsedInstances int `json:"usedInstances"`
+		UsedRAM       int `json:"usedRAM"`
+	} `json:"instance"`
+	Keypair struct {
+		MaxCount int `json:"maxCount"`
+	} `json:"keypair"`
+	Region string `json:"region"`
+	Volume struct {
+		MaxGigabytes   int `json:"maxGigabytes"`
+		MaxVolumeCount int `json:"maxVolumeCount"`
+		UsedGigabytes  int `json:"usedGigabytes"`
+		VolumeCount    int `json:"volumeCount"`
+	} `json:"volume"`
+}
+
+type wireBackup struct {
+	BackupName string   `json:"backupName"`
+	CreatedAt  string   `json:"createdAt"`
+	Cron       string   `json:"cron"`
+	Executions []string `json:"executions"`
+	ID         string   `json:"id"`
+	InstanceID string   `json:"instanceId"`
+	Name       string   `json:"name"`
+}
+
+func toWireRegion(region *Region) wireRegion {
+	wire := wireRegion{ContinentCode: "EU", DatacenterLocation: region.Name[:len(region.Name)-1], Name: region.Name,
+		Services: []wireRegionService{}, Status: region.Status}
+	for _, service := range region.Services {
+		wire.Services = append(wire.Services, wireRegionService{Name: service.Name, Status: service.Status})
+	}
+	return wire
+}
+
+func toWireFlavor(flavor Flavor) wireFlavor {
+	return wireFlavor{Available: true, Disk: flavor.DiskSpaceGB, ID: flavor.ID, Name: flavor.Name, OSType: flavor.OS,
+		RAM: flavor.MemoryGB, Region: flavor.Region, Type: "ovh.ssd.eg", Vcpus: flavor.Vcpus}
+}
+
+func toWireImage(image Image) wireImage {
+	return wireImage{CreationDate: image.CreationDate, ID: image.ID, MinDisk: image.MinDisk, Name: image.Name,
+		Region: image.Region, Status: image.Status, Type: image.OS, User: DefaultSSHUserName, Visibility: image.Visibility}
+}
+
+func toWireNetwork(network Network) wireNetwork {
+	return wireNetwork{ID: network.ID, Name: network.Name, Status: network.Status, Type: network.Type, VlanID: network.VlanID,
+		Regions: []wireNetworkRegion{{OpenstackID: "openstack-" + network.ID, Region: DefaultRegionName, Status: network.Status}}}
+}
+
+func toWireSshkey(key Sshkey) wireSshkey {
+	return wireSshkey{FingerPrint: key.Fingerprint, ID: key.ID, Name: key.Name, PublicKey: key.PublicKey, Regions: []string{DefaultRegionName}}
+}
+
+func toWireIPAddresses(ips IPs) []wireIPAddress {
+	addresses := []wireIPAddress{}
+	for _, ip := range ips {
+		addresses = append(addresses, wireIPAddress{IP: ip.IP, NetworkID: ip.NetworkID, Type: ip.Type, Version: ip.Version})
+	}
+	return addresses
+}
+
+func toWireMonthlyBilling(billing *MonthlyBilling) *wireMonthlyBilling {
+	if billing == nil {
+		return nil
+	}
+	return &wireMonthlyBilling{Since: billing.Since, Status: billing.Status}
+}
+
+func toWireInstance(instance Instance) wireInstance {
+	return wireInstance{Created: instance.Created, FlavorID: instance.Flavor.ID, ID: instance.ID, ImageID: instance.Image.ID,
+		IPAddresses: toWireIPAddresses(instance.IPAddresses), MonthlyBilling: toWireMonthlyBilling(instance.MonthlyBilling),
+		Name: instance.Name, Region: instance.Region, SshKeyID: instance.Sshkey.ID, Status: instance.Status}
+}
+
+func toWireInstanceDetail(instance *Instance) wireInstanceDetail {
+	key := toWireSshkey(instance.Sshkey)
+	return wireInstanceDetail{Created: instance.Created, Flavor: toWireFlavor(instance.Flavor), ID: instance.ID,
+		Image: toWireImage(instance.Image), IPAddresses: toWireIPAddresses(instance.IPAddresses),
+		MonthlyBilling: toWireMonthlyBilling(instance.MonthlyBilling), Name: instance.Name, Region: instance.Region,
+		SshKey: &key, Status: instance.Status}
+}
+
+func toWireVolume(volume *Volume) wireVolume {
+	return wireVolume{AttachedTo: append([]string{}, volume.AttachedTo...), CreationDate: volume.CreationDate, ID: volume.ID,
+		Name: volume.Name, Region: volume.Region, Size: volume.Size, Status: volume.Status, Type: volume.Type}
+}
+
+func toWireFailoverIP(ip FailoverIP) wireFailoverIP {
+	return wireFailoverIP{Block: ip.Block, ContinentCode: "EU", Geoloc: ip.GeoLoc, ID: ip.ID, IP: ip.IP, Progress: 100,
+		RoutedTo: ip.RoutedTo, Status: ip.Status, SubType: "cloud"}
+}
+
+func toWireQuota(quota Quota) (wire wireQuota) {
+	wire.Region = quota.Region
+	wire.Instance.MaxCores = quota.Instance.MaxCores
+	wire.Instance.MaxInstances = quota.Instance.MaxInstances
+	wire.Instance.MaxRAM = quota.Instance.MaxRAM
+	wire.Instance.UsedCores = quota.Instance.UsedCores
+	wire.Instance.UsedInstances = quota.Instance.UsedInstances
+	wire.Instance.UsedRAM = quota.Instance.UsedRAM
+	wire.Keypair.MaxCount = 100
+	wire.Volume.MaxGigabytes = quota.Volume.MaxGigabytes
+	wire.Volume.MaxVolumeCount = quota.Volume.MaxVolumeCount
+	wire.Volume.UsedGigabytes = quota.Volume.UsedGigabytes
+	wire.Volume.VolumeCount = quota.Volume.VolumeCount
+	return wire
+}
+
+func toWireBackup(workflow *BackupWorkflow) wireBackup {
+	return wireBackup{BackupName: workflow.BackupName, CreatedAt: workflow.CreatedAt, Cron: workflow.Cron,
+		Executions: []string{}, ID: workflow.ID, InstanceID: workflow.InstanceID, Name: workflow.Name}
+}
+
+// cloudRoutes maps the /cloud API calls to the fake backend
+func (s *ovhServer) cloudRoutes() []ovhRoute {
+	f := s.cloud
+	project := `^/cloud/project/([^/]+)`
+	route := func(method, pattern string, handler func(r *ovhRequest, params []string) (interface{}, error)) ovhRoute {
+		return ovhRoute{method, regexp.MustCompile(pattern + "$"), handler}
+	}
+	instanceAction := func(action func(projectID, instanceID string) error) func(r *ovhRequest, params []string) (interface{}, error) {
+		return func(r *ovhRequest, params []string) (interface{}, error) {
+			return nil, action(params[0], params[1])
+		}
+	}
+
+	return []ovhRoute{
+		// Projects
+		route("GET", `^/cloud/project`, func(r *ovhRequest, params []string) (interface{}, error) {
+			projects, err := f.GetProjects()
+			return append([]string{}, projects...), err
+		}),
+		route("GET", project, func(r *ovhRequest, params []string) (interface{}, error) {
+			found, err := f.GetProject(params[0])
+			if err != nil {
+				return nil, err
+			}
+			return wireProject{CreationDate: "2017-01-01T00:00:00Z", Description: found.Name, PlanCode: "project.2018",
+				ProjectID: found.ID, Status: found.Status, Access: "full"}, nil
+		}),
+		route("GET", project+`/region`, func(r *ovhRequest, params []string) (interface{}, error) {
+			regions, err := f.GetRegions(params[0])
+			return append([]string{}, regions...), err
+		}),
+		route("GET", project+`/region/([^/]+)`, func(r *ovhRequest, params []string) (interface{}, error) {
+			region, err := f.GetRegion(params[0], params[1])
+			if err != nil {
+				return nil, err
+			}
+			return toWireRegion(region), nil
+		}),
+		route("GET", project+`/quota`, func(r *ovhRequest, params []string) (interface{}, error) {
+			quotas, err := f.GetQuotas(params[0])
+			wire := []wireQuota{}
+			for _, quota := range quotas {
+				wire = append(wire, toWireQuota(quota))
+			}
+			return wire, err
+		}),
+		route("GET", project+`/flavor`, func(r *ovhRequest, params []string) (interface{}, error) {
+			flavors := []wireFlavor{}
+			for _, flavor := range f.Flavors {
+				if flavor.Region == r.URL.Query().Get("region") {
+					flavors = append(flavors, toWireFlavor(flavor))
+				}
+			}
+			return flavors, f.checkProject(params[0])
+		}),
+		route("GET", project+`/image`, func(r *ovhRequest, params []string) (interface{}, error) {
+			query := r.URL.Query()
+			images := []wireImage{}
+			for _, image := range f.Images {
+				if image.Region == query.Get("region") && (query.Get("osType") == "" || image.OS == query.Get("osType")) {
+					images = append(images, toWireImage(image))
+				}
+			}
+			return images, f.checkProject(params[0])
+		}),
+
+		route("GET", project+`/snapshot`, func(r *ovhRequest, params []string) (interface{}, error) {
+			snapshots, err := f.GetSnapshots(params[0], r.URL.Query().Get("region"))
+			images := []wireImage{}
+			for _, snapshot := range snapshots {
+				images = append(images, toWireImage(snapshot))
+			}
+			return images, err
+		}),
+		route("DELETE", project+`/snapshot/([^/]+)`, func(r *ovhRequest, params []string) (interface{}, error) {
+			return nil, f.DeleteSnapshot(params[0], params[1])
+		}),
+
+		// Networks
+		route("GET", project+`/network/public`, func(r *ovhRequest, params []string) (interface{}, error) {
+			id, err := f.GetPublicNetworkID(params[0])
+			return []wireNetwork{toWireNetwork(Network{ID: id, Name: "Ext-Net", Type: "public", Status: "ACTIVE"})}, err
+		}),
+		route("GET", project+`/network/private`, func(r *ovhRequest, params []string) (interface{}, error) {
+			networks := []wireNetwork{}
+			for _, network := range f.Networks {
+				networks = append(networks, toWireNetwork(network.Network))
+			}
+			sort.Slice(networks, func(i, j int) bool { return networks[i].ID < networks[j].ID })
+			return networks, f.checkProject(params[0])
+		}),
+		route("POST", project+`/network/private`, func(r *ovhRequest, params []string) (interface{}, error) {
+			var req wireNetworkCreation
+			if err := r.decode(&req, "name"); err != nil {
+				return nil, err
+			}
+			region := ""
+			if len(req.Regions) > 0 {
+				region = req.Regions[0]
+			}
+			network, err := f.CreatePrivateNetwork(params[0], req.Name, req.VlanID, region)
+			if err != nil {
+				return nil, err
+			}
+			return toWireNetwork(*network), nil
+		}),
+		route("GET", project+`/network/private/([^/]+)`, func(r *ovhRequest, params []string) (interface{}, error) {
+			network, err := f.GetPrivateNetwork(params[0], params[1])
+			if err != nil {
+				return nil, err
+			}
+			return toWireNetwork(*network), nil
+		}),
+		route("DELETE", project+`/network/private/([^/]+)`, func(r *ovhRequest, params []string) (interface{}, error) {
+			return nil, f.DeletePrivateNetwork(params[0], params[1])
+		}),
+		route("GET", project+`/network/private/([^/]+)/subnet`, func(r *ovhRequest, params []string) (interface{}, error) {
+			subnets, err := f.GetSubnets(params[0], params[1])
+			wire := []wireSubnet{}
+			for _, subnet := range subnets {
+				wire = append(wire, wireSubnet{CIDR: subnet.CIDR, GatewayIP: subnet.GatewayIP, ID: subnet.ID, IPPools: []wireIPPool{}})
+			}
+			return wire, err
+		}),
+		route("POST", project+`/network/private/([^/]+)/subnet`, func(r *ovhRequest, params []string) (interface{}, error) {
+			var req wireSubnetCreation
+			if err := r.decode(&req, "dhcp", "end", "network", "noGateway", "region", "start"); err != nil {
+				return nil, err
+			}
+			subnet, err := f.CreateSubnet(params[0], params[1], req.Region, req.Network, req.Start, req.End)
+			if err != nil {
+				return nil, err
+			}
+			pool := wireIPPool{DHCP: req.DHCP, End: req.End, Network: req.Network, Region: req.Region, Start: req.Start}
+			return wireSubnet{CIDR: subnet.CIDR, ID: subnet.ID, IPPools: []wireIPPool{pool}}, nil
+		}),
+
+		// SSH keys
+		route("GET", project+`/sshkey`, func(r *ovhRequest, params []string) (interface{}, error) {
+			keys := []wireSshkey{}
+			for _, key := range f.Sshkeys {
+				keys = append(keys, toWireSshkey(*key))
+			}
+			sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+			return keys, f.checkProject(params[0])
+		}),
+		route("POST", project+`/sshkey`, func(r *ovhRequest, params []string) (interface{}, error) {
+			var req wireSshkeyCreation
+			if err := r.decode(&req, "name", "publicKey"); err != nil {
+				return nil, err
+			}
+			key, err := f.CreateSshkey(params[0], req.Name, req.PublicKey)
+			if err != nil {
+				return nil, err
+			}
+			return toWireSshkey(*key), nil
+		}),
+		route("DELETE", project+`/sshkey/([^/]+)`, func(r *ovhRequest, params []string) (interface{}, error) {
+			return nil, f.DeleteSshkey(params[0], params[1])
+		}),
+
+		// Instances
+		route("GET", project+`/instance`, func(r *ovhRequest, params []string) (interface{}, error) {
+			instances, err := f.GetInstances(params[0], r.URL.Query().Get("region"))
+			wire := []wireInstance{}
+			for _, instance := range instances {
+				wire = append(wire, toWireInstance(instance))
+			}
+			sort.Slice(wire, func(i, j int) bool { return wire[i].ID < wire[j].ID })
+			return wire, err
+		}),
+		route("POST", project+`/instance`, func(r *ovhRequest, params []string) (interface{}, error) {
+			var req wireInstanceCreation
+			if err := r.decode(&req, "flavorId", "imageId", "name", "region"); err != nil {
+				return nil, err
+			}
+			var networks NetworkParams
+			for _, network := range req.Networks {
+				networks = append(networks, NetworkParam{ID: network.NetworkID, IP: network.IP})
+			}
+			instance, err := f.CreateInstance(params[0], req.Name, req.SshKeyID, req.FlavorID, req.ImageID, req.Region, networks, req.MonthlyBilling, req.UserData)
+			if err != nil {
+				return nil, err
+			}
+			return toWireInstanceDetail(instance), nil
+		}),
+		route("GET", project+`/instance/([^/]+)`, func(r *ovhRequest, params []string) (interface{}, error) {
+			instance, err := f.GetInstance(params[0], params[1])
+			if err != nil {
+				return nil, err
+			}
+			return toWireInstanceDetail(instance), nil
+		}),
+		route("DELETE", project+`/instance/([^/]+)`, instanceAction(f.DeleteInstance)),
+		route("POST", project+`/instance/([^/]+)/start`, instanceAction(f.StartInstance)),
+		route("POST", project+`/instance/([^/]+)/stop`, instanceAction(f.StopInstance)),
+		route("POST", project+`/instance/([^/]+)/shelve`, instanceAction(f.ShelveInstance)),
+		route("POST", project+`/instance/([^/]+)/unshelve`, instanceAction(f.UnshelveInstance)),
+		route("POST", project+`/instance/([^/]+)/reboot`, func(r *ovhRequest, params []string) (interface{}, error) {
+			var req wireReboot
+			if err := r.decode(&req, "type"); err != nil {
+				return nil, err
+			}
+			if req.Type != "soft" && req.Type != "hard" {
+				return nil, badRequest("Invalid reboot type %s", req.Type)
+			}
+			return nil, f.RebootInstance(params[0], params[1], req.Type == "hard")
+		}),
+		route("POST", project+`/instance/([^/]+)/snapshot`, func(r *ovhRequest, params []string) (interface{}, error) {
+			var req wireSnapshotCreation
+			if err := r.decode(&req, "snapshotName"); err != nil {
+				return nil, err
+			}
+			return nil, f.CreateSnapshot(params[0], params[1], req.SnapshotName)
+		}),
+		route("POST", project+`/instance/([^/]+)/resize`, func(r *ovhRequest, params []string) (interface{}, error) {
+			var req wireResize
+			if err := r.decode(&req, "flavorId"); err != nil {
+				return nil, err
+			}
+			return nil, f.ResizeInstance(params[0], params[1], req.FlavorID)
+		}),
+		route("POST", project+`/instance/([^/]+)/rescueMode`, func(r *ovhRequest, params []string) (interface{}, error) {
+			var req wireRescueMode
+			if err := r.decode(&req, "rescue"); err != nil {
+				return nil, err
+			}
+			rescue, err := f.RescueInstance(params[0], params[1], req.ImageID, req.Rescue)
+			if err != nil {
+				return nil, err
+			}
+			if rescue.AdminPassword == "" {
+				return wireRescuePassword{}, nil
+			}
+			return wireRescuePassword{&rescue.AdminPassword}, nil
+		}),
+		route("POST", project+`/instance/([^/]+)/reinstall`, func(r *ovhRequest, params []string) (interface{}, error) {
+			var req wireReinstall
+			if err := r.decode(&req, "imageId"); err != nil {
+				return nil, err
+			}
+			instance, err := f.ReinstallInstance(params[0], params[1], req.ImageID)
+			if err != nil {
+				return nil, err
+			}
+			return toWireInstanceDetail(instance), nil
+		}),
+		route("POST", project+`/instance/([^/]+)/activeMonthlyBilling`, func(r *ovhRequest, params []string) (interface{}, error) {
+			instance, err := f.ActivateMonthlyBilling(params[0], params[1])
+			if err != nil {
+				return nil, err
+			}
+			return toWireInstanceDetail(instance), nil
+		}),
+
+		// Volumes
+		route("POST", project+`/volume`, func(r *ovhRequest, params []string) (interface{}, error) {
+			var req wireVolumeCreation
+			if err := r.decode(&req, "region", "size"); err != nil {
+				return nil, err
+			}
+			volume, err := f.CreateVolume(params[0], req.Region, req.Name, req.Type, req.Size)
+			if err != nil {
+				return nil, err
+			}
+			return toWireVolume(volume), nil
+		}),
+		route("GET", project+`/volume/([^/]+)`, func(r *ovhRequest, params []string) (interface{}, error) {
+			volume, err := f.GetVolume(params[0], params[1])
+			if err != nil {
+				return nil, err
+			}
+			return toWireVolume(volume), nil
+		}),
+		route("DELETE", project+`/volume/([^/]+)`, func(r *ovhRequest, params []string) (interface{}, error) {
+			return nil, f.DeleteVolume(params[0], params[1])
+		}),
+		route("POST", project+`/volume/([^/]+)/(attach|detach)`, func(r *ovhRequest, params []string) (interface{}, error) {
+			var req wireInstanceID
+			if err := r.decode(&req, "instanceId"); err != nil {
+				return nil, err
+			}
+			if params[2] == "attach" {
+				return nil, f.AttachVolume(params[0], params[1], req.InstanceID)
+			}
+			return nil, f.DetachVolume(params[0], params[1], req.InstanceID)
+		}),
+
+		// Failover IPs
+		route("GET", project+`/ip/failover`, func(r *ovhRequest, params []string) (interface{}, error) {
+			ips, err := f.GetFailoverIPs(params[0])
+			wire := []wireFailoverIP{}
+			for _, ip := range ips {
+				wire = append(wire, toWireFailoverIP(ip))
+			}
+			return wire, err
+		}),
+		route("POST", project+`/ip/failover/([^/]+)/attach`, func(r *ovhRequest, params []string) (interface{}, error) {
+			var req wireInstanceID
+			if err := r.decode(&req, "instanceId"); err != nil {
+				return nil, err
+			}
+			ip, err := f.AttachFailoverIP(params[0], params[1], req.InstanceID)
+			if err != nil {
+				return nil, err
+			}
+			return toWireFailoverIP(*ip), nil
+		}),
+
+		// Backups
+		route("POST", project+`/region/([^/]+)/workflow/backup`, func(r *ovhRequest, params []string) (interface{}, error) {
+			var req wireBackupCreation
+			if err := r.decode(&req, "cron", "instanceId", "name", "rotation"); err != nil {
+				return nil, err
+			}
+			workflow, err := f.CreateBackupWorkflow(params[0], params[1], req.InstanceID, req.Name, req.Cron, req.Rotation)
+			if err != nil {
+				return nil, err
+			}
+			return toWireBackup(workflow), nil
+		}),
+		route("DELETE", project+`/region/([^/]+)/workflow/backup/([^/]+)`, func(r *ovhRequest, params []string) (interface{}, error) {
+			return nil, f.DeleteBackupWorkflow(params[0], params[1], params[2])
+		}),
+	}
+}
+
+// newTestAPI returns an API client of server, signing with secret
+func newTestAPI(t *testing.T, server *ovhServer, secret string) *API {
+	api, err := NewAPI(server.URL+"/1.0", testApplicationKey, secret, testConsumerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api
+}
+
+func TestOVHServerSignature(t *testing.T) {
+	server := newOVHServer(t, newFakeCloud())
+
+	projects, err := newTestAPI(t, server, testApplicationSecret).GetProjects()
+	if err != nil || len(projects) != 1 || projects[0] != "project-1" {
+		t.Errorf("unexpected projects %v, error %v", projects, err)
+	}
+
+	_, err = newTestAPI(t, server, "wrong-secret").GetProjects()
+	if apierror, ok := err.(*ovh.APIError); !ok || apierror.Code != 400 {
+		t.Errorf("expected an invalid signature error, got %v", err)
+	}
+}
+
+func TestOVHServerStrictFields(t *testing.T) {
+	server := newOVHServer(t, newFakeCloud())
+	api := newTestAPI(t, server, testApplicationSecret)
+
+	// encoding/json would accept this mismatched case
+	err := api.client.Post("/cloud/project/project-1/sshkey", map[string]string{"name": "key", "publicKEY": "ssh-rsa AAAA"}, nil)
+	if apierror, ok := err.(*ovh.APIError); !ok || apierror.Code != 400 || !strings.Contains(apierror.Message, "publicKEY") {
+		t.Errorf("expected an invalid property error, got %v", err)
+	}
+
+	err = api.client.Post("/cloud/project/project-1/sshkey", map[string]string{"name": "key"}, nil)
+	if apierror, ok := err.(*ovh.APIError); !ok || apierror.Code != 400 || !strings.Contains(apierror.Message, "publicKey") {
+		t.Errorf("expected a missing property error, got %v", err)
+	}
+}
+
+// TestAPIWire runs the driver against the stand-in server, through the real
+// API client
+func TestAPIWire(t *testing.T) {
+	cloud := newFakeCloud()
+	server := newOVHServer(t, cloud)
+	d := newTestDriver(t, cloud, testFlags{
+		"ovh-endpoint":               server.URL + "/1.0",
+		"ovh-application-key":        testApplicationKey,
+		"ovh-application-secret":     testApplicationSecret,
+		"ovh-consumer-key":           testConsumerKey,
+		"ovh-private-network":        []string{"backend"},
+		"ovh-private-network-create": true,
+		"ovh-volume-size":            10,
+		"ovh-ipv6":                   true,
+		"ovh-userdata-inline":        "#!/bin/sh\necho hello",
+		"ovh-billing-period":         "monthly",
+		"ovh-backup-cron":            "0 3 * * *",
+	})
+	d.client = nil
+
+	checkError(t, d.PreCreateCheck(), "")
+	checkError(t, d.Create(), "")
+	if _, ok := d.client.(*API); !ok {
+		t.Fatalf("driver does not use the API client: %T", d.client)
+	}
+
+	instance := cloud.Instances[d.InstanceID]
+	if instance == nil || instance.Flavor.ID != "flavor-1" || instance.Image.ID != "image-1" || instance.Sshkey.ID != d.KeyPairID {
+		t.Fatalf("instance was not created as requested: %+v", instance)
+	}
+	if instance.MonthlyBilling == nil || !strings.Contains(instance.UserData, "echo hello") || len(instance.NetworkParams) != 2 || instance.NetworkParams[0].ID != d.PrivateNetworks[0].ID {
+		t.Errorf("instance was not configured as requested: %+v", instance)
+	}
+	if network := cloud.Networks[d.PrivateNetworks[0].ID]; network == nil || len(network.Subnets) != 1 {
+		t.Errorf("private network was not created with a subnet: %+v", network)
+	}
+	if ip, _ := d.GetIP(); ip != instance.IPAddresses[1].IP {
+		t.Errorf("unexpected IP %s, expected public IP %s", ip, instance.IPAddresses[1].IP)
+	}
+	if d.IPv6Address != instance.IPAddresses[2].IP {
+		t.Errorf("unexpected IPv6 %s, expected %s", d.IPv6Address, instance.IPAddresses[2].IP)
+	}
+	if len(d.VolumeIDs) != 1 || cloud.Volumes[d.VolumeIDs[0]].Status != "in-use" || len(cloud.Workflows) != 1 {
+		t.Errorf("volume or backups not set up: %v, %v", d.VolumeIDs, cloud.Workflows)
+	}
+
+	checkError(t, d.Stop(), "")
+	if st, err := d.GetState(); st != state.Stopped || err != nil {
+		t.Errorf("unexpected state %s after stop, error %v", st, err)
+	}
+	checkError(t, d.Start(), "")
+	if st, err := d.GetState(); st != state.Running || err != nil {
+		t.Errorf("unexpected state %s after start, error %v", st, err)
+	}
+
+	checkError(t, d.Remove(), "")
+	if resources := liveResources(cloud); len(resources) != 0 {
+		t.Errorf("resources left after remove: %v", resources)
+	}
+}
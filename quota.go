@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+// quotaCheck is a resource needed by the machine, compared to the project limits
+type quotaCheck struct {
+	Name   string
+	Unit   string
+	Needed int
+	Used   int
+	Max    int
+}
+
+// exhausted reports whether the project lacks room for the needed amount. A
+// zero limit is unknown and never exhausted
+func (q quotaCheck) exhausted() bool {
+	return q.Max > 0 && q.Used+q.Needed > q.Max
+}
+
+func (q quotaCheck) String() string {
+	return fmt.Sprintf("%s: %d%s needed, %d%s of %d%s used", q.Name, q.Needed, q.Unit, q.Used, q.Unit, q.Max, q.Unit)
+}
+
+// checkQuotas ensures the project has room in the region for an instance of
+// flavor and the requested volumes
+func (d *Driver) checkQuotas(flavor *Flavor) error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	quotas, err := client.GetQuotas(d.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	var quota *Quota
+	for i := range quotas {
+		if quotas[i].Region == d.RegionName {
+			quota = &quotas[i]
+			break
+		}
+	}
+	if quota == nil {
+		log.Debug("No quota found for region ", d.RegionName)
+		return nil
+	}
+
+	volumes, err := d.getVolumeSpecs()
+	if err != nil {
+		return err
+	}
+	volumeSize := 0
+	for _, volume := range volumes {
+		volumeSize += volume.Size
+	}
+
+	// Flavor RAM is in GB, quotas are in MB
+	checks := []quotaCheck{
+		{"instances", "", 1, quota.Instance.UsedInstances, quota.Instance.MaxInstances},
+		{"cores", "", flavor.Vcpus, quota.Instance.UsedCores, quota.Instance.MaxCores},
+		{"RAM", "MB", flavor.MemoryGB * 1024, quota.Instance.UsedRAM, quota.Instance.MaxRAM},
+	}
+	if len(volumes) > 0 {
+		checks = append(checks,
+			quotaCheck{"volumes", "", len(volumes), quota.Volume.VolumeCount, quota.Volume.MaxVolumeCount},
+			quotaCheck{"volume storage", "GB", volumeSize, quota.Volume.UsedGigabytes, quota.Volume.MaxGigabytes},
+		)
+	}
+
+	var exhausted []string
+	for _, check := range checks {
+		log.Debug("Quota", check.String())
+		if check.exhausted() {
+			exhausted = append(exhausted, "  - "+check.String())
+		}
+	}
+	if len(exhausted) > 0 {
+		return fmt.Errorf("Not enough quota in region %s for flavor %s:\n%s\nTo raise quotas, please visit %s", d.RegionName, flavor.Name, strings.Join(exhausted, "\n"), CustomerInterface)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// Shaped like GET /cloud/project/{serviceName}/quota and /flavor responses
+const (
+	testQuotasJSON = `[{
+		"region": "GRA1",
+		"instance": {"maxCores": 20, "maxInstances": 10, "maxRam": 40960, "usedCores": %d, "usedInstances": 2, "usedRAM": %d},
+		"keypair": {"maxCount": 100},
+		"volume": {"maxGigabytes": 10000, "usedGigabytes": %d, "volumeCount": 1, "maxVolumeCount": 20}
+	}, {
+		"region": "BHS1",
+		"instance": {"maxCores": 0, "maxInstances": 0, "maxRam": 0, "usedCores": 0, "usedInstances": 0, "usedRAM": 0},
+		"keypair": {"maxCount": 100},
+		"volume": {"maxGigabytes": 0, "usedGigabytes": 0, "volumeCount": 0, "maxVolumeCount": 0}
+	}]`
+	testFlavorJSON = `{
+		"id": "0a2c1e8f-3c5b-4b8a-9a52-2f4c8f0e1b7d",
+		"name": "b2-7",
+		"region": "GRA1",
+		"osType": "linux",
+		"vcpus": 2,
+		"ram": 7,
+		"disk": 50,
+		"type": "ovh.ssd.eg",
+		"available": true,
+		"inboundBandwidth": 250,
+		"outboundBandwidth": 250
+	}`
+)
+
+func TestCheckQuotas(t *testing.T) {
+	tests := []struct {
+		name     string
+		region   string
+		usedRAM  int
+		cores    int
+		storage  int
+		flags    testFlags
+		expected string
+	}{
+		{name: "room left", usedRAM: 4096, cores: 2},
+		{name: "RAM exhausted", usedRAM: 35000, cores: 2, expected: "RAM: 7168MB needed, 35000MB of 40960MB used"},
+		{name: "RAM just fits", usedRAM: 40960 - 7168, cores: 2},
+		{name: "cores exhausted", usedRAM: 4096, cores: 19, expected: "cores: 2 needed, 19 of 20 used"},
+		{name: "volume storage exhausted", usedRAM: 4096, cores: 2, storage: 9995, flags: testFlags{"ovh-volume-size": 10}, expected: "volume storage: 10GB needed, 9995GB of 10000GB used"},
+		{name: "unknown limits", region: "BHS1", usedRAM: 40960, cores: 20},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cloud := newFakeCloud()
+			if err := json.Unmarshal([]byte(fmt.Sprintf(testQuotasJSON, test.cores, test.usedRAM, test.storage)), &cloud.Quotas); err != nil {
+				t.Fatal(err)
+			}
+			var flavor Flavor
+			if err := json.Unmarshal([]byte(testFlavorJSON), &flavor); err != nil {
+				t.Fatal(err)
+			}
+
+			d := newTestDriver(t, cloud, test.flags)
+			d.ProjectID = "project-1"
+			if test.region != "" {
+				d.RegionName = test.region
+			}
+			checkError(t, d.checkQuotas(&flavor), test.expected)
+		})
+	}
+}
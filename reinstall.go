@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+)
+
+const (
+	// reinstallTimeout is the time in seconds to wait for a reinstall to complete
+	reinstallTimeout = 1200
+)
+
+// Reinstall reinstalls the machine from another image. The instance, its
+// addresses and its SSH key are kept, the machine must then be provisioned again
+func (d *Driver) Reinstall(imageName string) error {
+	if d.Rescue {
+		return fmt.Errorf("Machine %s is in rescue mode. Please exit rescue mode first", d.MachineName)
+	}
+
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	image, err := client.GetImageByName(d.ProjectID, d.RegionName, imageName)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Reinstalling OVH instance ", d.InstanceID, " from image ", image.Name, "...")
+	instance, err := client.ReinstallInstance(d.ProjectID, d.InstanceID, image.ID)
+	if err != nil {
+		return err
+	}
+
+	// Start from the returned status, the instance is in REBUILD state until
+	// it runs the new image
+	err = mcnutils.WaitForSpecificOrError(func() (bool, error) {
+		if instance == nil {
+			instance, err = client.GetInstance(d.ProjectID, d.InstanceID)
+			if err != nil {
+				return true, err
+			}
+		}
+		log.Debug("Machine", map[string]interface{}{
+			"Name":  d.MachineName,
+			"State": instance.Status,
+		})
+
+		switch instance.Status {
+		case "ERROR":
+			return true, fmt.Errorf("Instance reinstall failed. Instance is in ERROR state")
+		case "ACTIVE":
+			return true, nil
+		}
+		instance = nil
+		return false, nil
+	}, (reinstallTimeout / 4), statusPollInterval)
+	if err != nil {
+		return err
+	}
+
+	d.ImageID = image.ID
+	d.updatePrivateIPAddresses(instance)
+	return d.updateIPAddress(instance)
+}
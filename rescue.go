@@ -0,0 +1,77 @@
+package main
+
+import (
+	"github.com/docker/machine/libmachine/log"
+)
+
+// getRescueIP returns the public address of the instance, floating IPs are not
+// configured by rescue systems
+func (d *Driver) getRescueIP() string {
+	for _, ip := range d.InstanceIPs {
+		if ip.Type == "public" && getIPVersion(ip) == 4 {
+			return ip.IP
+		}
+	}
+	return ""
+}
+
+// EnterRescue reboots the machine on a rescue system and waits until it is
+// reachable. An empty imageName selects the default OVH rescue image
+func (d *Driver) EnterRescue(imageName string) error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	imageID := ""
+	if imageName != "" {
+		image, err := client.GetImageByName(d.ProjectID, d.RegionName, imageName)
+		if err != nil {
+			return err
+		}
+		imageID = image.ID
+	}
+
+	log.Info("Booting OVH instance ", d.InstanceID, " in rescue mode...")
+	rescueMode, err := client.RescueInstance(d.ProjectID, d.InstanceID, imageID, true)
+	if err != nil {
+		return err
+	}
+
+	instance, err := d.waitForInstanceStatus("RESCUE")
+	if err != nil {
+		return err
+	}
+	d.InstanceIPs = instance.IPAddresses
+
+	d.Rescue = true
+	d.RescuePassword = ""
+	if rescueMode != nil {
+		d.RescuePassword = rescueMode.AdminPassword
+	}
+	return nil
+}
+
+// ExitRescue reboots the machine on its own disk and waits until it is active
+func (d *Driver) ExitRescue() error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	log.Info("Booting OVH instance ", d.InstanceID, " out of rescue mode...")
+	_, err = client.RescueInstance(d.ProjectID, d.InstanceID, "", false)
+	if err != nil {
+		return err
+	}
+
+	instance, err := d.waitForInstanceStatus("ACTIVE")
+	if err != nil {
+		return err
+	}
+
+	d.Rescue = false
+	d.RescuePassword = ""
+	d.updatePrivateIPAddresses(instance)
+	return d.updateIPAddress(instance)
+}
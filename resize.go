@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+)
+
+const (
+	// resizeTimeout is the time in seconds to wait for a resize to complete
+	resizeTimeout = 1200
+)
+
+// Resize moves the machine to another flavor. The disk is kept, hence flavors
+// with a smaller disk are refused
+func (d *Driver) Resize(flavorName string) error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	// Resolve flavors
+	flavor, err := client.GetFlavorByName(d.ProjectID, d.RegionName, flavorName)
+	if err != nil {
+		return err
+	}
+	if flavor.ID == d.FlavorID {
+		log.Info("Machine ", d.MachineName, " already uses flavor ", flavor.Name)
+		return nil
+	}
+	current, err := client.GetFlavorByName(d.ProjectID, d.RegionName, d.FlavorID)
+	if err != nil {
+		return err
+	}
+	if flavor.DiskSpaceGB < current.DiskSpaceGB {
+		return fmt.Errorf("Flavor %s has a %dGB disk, which cannot hold the %dGB disk of flavor %s. Please select a flavor with a bigger disk", flavor.Name, flavor.DiskSpaceGB, current.DiskSpaceGB, current.Name)
+	}
+
+	// Resize and wait until the instance runs the new flavor
+	log.Info("Resizing OVH instance ", d.InstanceID, " to flavor ", flavor.Name, "...")
+	err = client.ResizeInstance(d.ProjectID, d.InstanceID, flavor.ID)
+	if err != nil {
+		return err
+	}
+
+	err = mcnutils.WaitForSpecificOrError(func() (bool, error) {
+		instance, err := client.GetInstance(d.ProjectID, d.InstanceID)
+		if err != nil {
+			return true, err
+		}
+		log.Debug("Machine", map[string]interface{}{
+			"Name":     d.MachineName,
+			"State":    instance.Status,
+			"FlavorID": instance.Flavor.ID,
+		})
+
+		switch instance.Status {
+		case "ERROR":
+			return true, fmt.Errorf("Instance resize failed. Instance is in ERROR state")
+		case "ACTIVE", "SHUTOFF":
+			return instance.Flavor.ID == flavor.ID, nil
+		}
+		return false, nil
+	}, (resizeTimeout / 4), statusPollInterval)
+	if err != nil {
+		return err
+	}
+
+	d.FlavorID = flavor.ID
+	d.FlavorName = flavor.Name
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/docker/machine/libmachine/log"
+)
+
+// rollbackStep tears down a single resource created on OVH Cloud
+type rollbackStep struct {
+	name string
+	undo func() error
+}
+
+// rollback tracks the resources created so far, so that they can be torn
+// down in reverse order if the machine creation fails
+type rollback struct {
+	steps []rollbackStep
+}
+
+// add registers the undo function of a newly created resource
+func (r *rollback) add(name string, undo func() error) {
+	r.steps = append(r.steps, rollbackStep{name: name, undo: undo})
+}
+
+// run tears down every registered resource, most recent first. Errors are
+// logged rather than returned so that every resource gets a chance to be
+// cleaned up
+func (r *rollback) run() {
+	for i := len(r.steps) - 1; i >= 0; i-- {
+		step := r.steps[i]
+		if err := step.undo(); err != nil {
+			log.Warnf("Failed to clean up %s: %s", step.name, err)
+			continue
+		}
+		log.Infof("Cleaned up %s", step.name)
+	}
+	r.steps = nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+)
+
+const (
+	// snapshotTimeout is the time in seconds to wait for a snapshot to be active
+	snapshotTimeout = 1200
+)
+
+// Snapshot creates a private image of the machine named name and waits until
+// it is active. New machines may then be created from it with --ovh-image
+func (d *Driver) Snapshot(name string) (image *Image, err error) {
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	// Snapshots are only found by name once created, the name must be new
+	snapshots, err := client.GetSnapshots(d.ProjectID, d.RegionName)
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.Name == name {
+			return nil, fmt.Errorf("Snapshot name '%s' is already used by image %s. Please choose another name", name, snapshot.ID)
+		}
+	}
+
+	log.Info("Creating snapshot ", name, " of OVH instance ", d.InstanceID, "...")
+	err = client.CreateSnapshot(d.ProjectID, d.InstanceID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return image, mcnutils.WaitForSpecificOrError(func() (bool, error) {
+		snapshots, err := client.GetSnapshots(d.ProjectID, d.RegionName)
+		if err != nil {
+			return true, err
+		}
+
+		for _, snapshot := range snapshots {
+			if snapshot.Name != name {
+				continue
+			}
+			log.Debug("Snapshot", map[string]interface{}{
+				"ImageID": snapshot.ID,
+				"State":   snapshot.Status,
+			})
+
+			switch snapshot.Status {
+			case "active":
+				image = &snapshot
+				return true, nil
+			case "error", "killed", "deleted":
+				return true, fmt.Errorf("Snapshot %s failed. Image %s is in %s state", name, snapshot.ID, snapshot.Status)
+			}
+		}
+
+		return false, nil
+	}, (snapshotTimeout / 4), statusPollInterval)
+}
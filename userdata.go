@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+const (
+	// maxUserDataSize is the largest user data accepted by OVH Cloud
+	// instances, once base64 encoded
+	maxUserDataSize = 65535
+)
+
+// userDataPart is a single part of a cloud-init multi-part user data
+type userDataPart struct {
+	Header  textproto.MIMEHeader
+	Content string
+}
+
+// newUserDataPart returns a user data part, guessing its content type from its content
+func newUserDataPart(content string) userDataPart {
+	var contentType string
+	switch {
+	case strings.HasPrefix(content, "#cloud-config"):
+		contentType = "text/cloud-config"
+	case strings.HasPrefix(content, "#include"):
+		contentType = "text/x-include-url"
+	case strings.HasPrefix(content, "#cloud-boothook"):
+		contentType = "text/cloud-boothook"
+	case strings.HasPrefix(content, "#part-handler"):
+		contentType = "text/part-handler"
+	default:
+		contentType = "text/x-shellscript"
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Type", contentType+`; charset="utf-8"`)
+	return userDataPart{Header: header, Content: content}
+}
+
+// parseUserData splits user data into its parts. Multi-part MIME user data
+// yields one part per MIME part, anything else is a single part
+func parseUserData(content string) (parts []userDataPart, err error) {
+	if !strings.HasPrefix(content, "Content-Type: multipart/") && !strings.HasPrefix(content, "MIME-Version:") {
+		return []userDataPart{newUserDataPart(content)}, nil
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid multi-part user data: %s", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, fmt.Errorf("Invalid multi-part user data: unexpected content type '%s'", msg.Header.Get("Content-Type"))
+	}
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid multi-part user data: %s", err)
+		}
+
+		body, err := ioutil.ReadAll(part)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid multi-part user data: %s", err)
+		}
+		parts = append(parts, userDataPart{Header: part.Header, Content: string(body)})
+	}
+
+	return parts, nil
+}
+
+// buildUserData assembles parts into user data. A single part is sent as-is,
+// several parts are merged into a multi-part MIME document
+func buildUserData(parts []userDataPart) (string, error) {
+	if len(parts) == 0 {
+		return "", nil
+	}
+	if len(parts) == 1 {
+		return parts[0].Content, nil
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range parts {
+		w, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return "", err
+		}
+		if _, err = w.Write([]byte(part.Content)); err != nil {
+			return "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	header := fmt.Sprintf("Content-Type: multipart/mixed; boundary=\"%s\"\r\nMIME-Version: 1.0\r\n\r\n", writer.Boundary())
+	return header + body.String(), nil
+}
+
+// getUserDataSnippets returns the user data snippets generated by the driver
+func (d *Driver) getUserDataSnippets() (snippets []userDataPart, err error) {
+	if len(d.PrivateNetworkNames) > 0 {
+		snippets = append(snippets, newUserDataPart(vrackConfigScript))
+	}
+
+	if d.FloatingIP != "" {
+		snippets = append(snippets, newUserDataPart(getFloatingIPScript(d.FloatingIP)))
+	}
+
+	if len(d.AllowedCIDRs) > 0 {
+		firewall, err := d.getFirewallUserData()
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, newUserDataPart(firewall))
+	}
+
+	return snippets, nil
+}
+
+// getUserData returns the user data to send to the new instance, if any.
+// Driver generated snippets are merged with the user provided user data
+func (d *Driver) getUserData() (string, error) {
+	snippets, err := d.getUserDataSnippets()
+	if err != nil {
+		return "", err
+	}
+
+	if d.UserDataFile != "" && d.UserDataInline != "" {
+		return "", fmt.Errorf("Options '--ovh-userdata' and '--ovh-userdata-inline' are mutually exclusive")
+	}
+
+	content := d.UserDataInline
+	if d.UserDataFile != "" {
+		raw, err := ioutil.ReadFile(d.UserDataFile)
+		if err != nil {
+			return "", fmt.Errorf("Could not read user data file '%s': %s", d.UserDataFile, err)
+		}
+		content = string(raw)
+	}
+
+	var parts []userDataPart
+	if content != "" {
+		userParts, err := parseUserData(content)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, userParts...)
+	}
+
+	// User data is sent as-is unless there is something to merge
+	userData := content
+	if len(snippets) > 0 {
+		userData, err = buildUserData(append(parts, snippets...))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if size := base64.StdEncoding.EncodedLen(len(userData)); size > maxUserDataSize {
+		return "", fmt.Errorf("User data is too large: %d bytes once base64 encoded, at most %d bytes are allowed", size, maxUserDataSize)
+	}
+
+	return userData, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetUserData(t *testing.T) {
+	userDataFile := filepath.Join(t.TempDir(), "user-data.txt")
+	if err := ioutil.WriteFile(userDataFile, []byte("#cloud-config\npackages: [htop]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	multipartUserData := "Content-Type: multipart/mixed; boundary=\"user\"\r\nMIME-Version: 1.0\r\n\r\n" +
+		"--user\r\nContent-Type: text/cloud-config\r\n\r\n#cloud-config\npackages: [htop]\r\n" +
+		"--user\r\nContent-Type: text/x-shellscript\r\n\r\n#!/bin/sh\necho hello\r\n" +
+		"--user--\r\n"
+
+	tests := []struct {
+		name     string
+		driver   Driver
+		expected string
+		types    []string
+		contents []string
+		err      string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:     "plain script",
+			driver:   Driver{UserDataInline: "#!/bin/sh\necho hello"},
+			expected: "#!/bin/sh\necho hello",
+		},
+		{
+			name:     "cloud-config file",
+			driver:   Driver{UserDataFile: userDataFile},
+			expected: "#cloud-config\npackages: [htop]\n",
+		},
+		{
+			name:     "driver snippet only",
+			driver:   Driver{PrivateNetworkNames: []string{"backend"}},
+			expected: vrackConfigScript,
+		},
+		{
+			name:     "script merged with driver snippets",
+			driver:   Driver{UserDataInline: "#!/bin/sh\necho hello", PrivateNetworkNames: []string{"backend"}, FloatingIP: "198.51.100.7"},
+			types:    []string{"text/x-shellscript", "text/x-shellscript", "text/x-shellscript"},
+			contents: []string{"echo hello", "Configure vRack interfaces", "198.51.100.7/32"},
+		},
+		{
+			name:     "cloud-config merged with driver snippets",
+			driver:   Driver{UserDataFile: userDataFile, AllowedCIDRs: []string{"203.0.113.0/24"}},
+			types:    []string{"text/cloud-config", "text/cloud-boothook"},
+			contents: []string{"packages: [htop]", "iptables -A OVH-MACHINE"},
+		},
+		{
+			name:     "multi-part merged with driver snippets",
+			driver:   Driver{UserDataInline: multipartUserData, PrivateNetworkNames: []string{"backend"}},
+			types:    []string{"text/cloud-config", "text/x-shellscript", "text/x-shellscript"},
+			contents: []string{"packages: [htop]", "echo hello", "Configure vRack interfaces"},
+		},
+		{
+			name:   "mutually exclusive",
+			driver: Driver{UserDataFile: userDataFile, UserDataInline: "#!/bin/sh"},
+			err:    "Options '--ovh-userdata' and '--ovh-userdata-inline' are mutually exclusive",
+		},
+		{
+			name:   "missing file",
+			driver: Driver{UserDataFile: userDataFile + ".missing"},
+			err:    "Could not read user data file",
+		},
+		{
+			name:   "invalid multi-part",
+			driver: Driver{UserDataInline: "MIME-Version: 1.0\r\nContent-Type: text/plain\r\n\r\nhello", PrivateNetworkNames: []string{"backend"}},
+			err:    "Invalid multi-part user data: unexpected content type 'text/plain'",
+		},
+		{
+			name:   "too large once encoded",
+			driver: Driver{UserDataInline: "#!/bin/sh\n" + strings.Repeat("#", 50000)},
+			err:    "User data is too large: 66680 bytes once base64 encoded, at most 65535 bytes are allowed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			userData, err := test.driver.getUserData()
+			checkError(t, err, test.err)
+			if test.types == nil {
+				if userData != test.expected {
+					t.Errorf("expected user data %q, got %q", test.expected, userData)
+				}
+				return
+			}
+
+			parts, err := parseUserData(userData)
+			if err != nil {
+				t.Fatalf("merged user data does not parse: %s\n%s", err, userData)
+			}
+			if len(parts) != len(test.types) {
+				t.Fatalf("expected %d parts, got %d:\n%s", len(test.types), len(parts), userData)
+			}
+			for i, part := range parts {
+				if contentType := part.Header.Get("Content-Type"); !strings.HasPrefix(contentType, test.types[i]) {
+					t.Errorf("part %d: expected content type %s, got %s", i, test.types[i], contentType)
+				}
+				if !strings.Contains(part.Content, test.contents[i]) {
+					t.Errorf("part %d: expected %q in %q", i, test.contents[i], part.Content)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+)
+
+// volumeSpec describes a block storage volume to attach at machine creation
+type volumeSpec struct {
+	Size int
+	Type string
+}
+
+// parseVolumeSpec parses a volume definition of the form SIZE[:TYPE], SIZE being in GB
+func parseVolumeSpec(spec, defaultType string) (volume volumeSpec, err error) {
+	parts := strings.SplitN(spec, ":", 2)
+	volume.Size, err = strconv.Atoi(parts[0])
+	if err != nil || volume.Size <= 0 {
+		return volume, fmt.Errorf("Invalid volume '%s'. Volumes are defined as SIZE[:TYPE] with SIZE in GB", spec)
+	}
+
+	volume.Type = defaultType
+	if len(parts) == 2 {
+		volume.Type = parts[1]
+	}
+	if volume.Type != "classic" && volume.Type != "high-speed" {
+		return volume, fmt.Errorf("Invalid volume type '%s'. Please select one of 'classic', 'high-speed'", volume.Type)
+	}
+
+	return volume, nil
+}
+
+// getVolumeSpecs returns the list of volumes requested on the command line
+func (d *Driver) getVolumeSpecs() (volumes []volumeSpec, err error) {
+	if d.VolumeSize > 0 {
+		volume, err := parseVolumeSpec(strconv.Itoa(d.VolumeSize), d.VolumeType)
+		if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, volume)
+	}
+
+	for _, spec := range d.Volumes {
+		volume, err := parseVolumeSpec(spec, d.VolumeType)
+		if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, volume)
+	}
+
+	return volumes, nil
+}
+
+// waitForVolumeStatus waits until volume reaches one of statuses
+func (d *Driver) waitForVolumeStatus(volumeID string, statuses ...string) (volume *Volume, err error) {
+	return volume, mcnutils.WaitForSpecificOrError(func() (bool, error) {
+		volume, err = d.client.GetVolume(d.ProjectID, volumeID)
+		if err != nil {
+			return true, err
+		}
+		log.Debug("Volume", map[string]interface{}{
+			"VolumeID": volumeID,
+			"State":    volume.Status,
+		})
+
+		if strings.HasPrefix(volume.Status, "error") {
+			return true, fmt.Errorf("Volume %s is in %s state", volumeID, volume.Status)
+		}
+
+		for _, status := range statuses {
+			if volume.Status == status {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}, (statusTimeout / 4), statusPollInterval)
+}
+
+// createVolumes creates the requested block storage volumes and attaches them
+// to the instance. Created volumes are registered in created for rollback
+func (d *Driver) createVolumes(created *rollback) error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	volumes, err := d.getVolumeSpecs()
+	if err != nil {
+		return err
+	}
+
+	for i, spec := range volumes {
+		name := fmt.Sprintf("%s-volume-%d", d.MachineName, i)
+		log.Info("Creating OVH volume ", name, "...")
+		volume, err := client.CreateVolume(d.ProjectID, d.RegionName, name, spec.Type, spec.Size)
+		if err != nil {
+			return err
+		}
+
+		volumeID := volume.ID
+		d.VolumeIDs = append(d.VolumeIDs, volumeID)
+		created.add("volume "+volumeID, func() error {
+			return d.removeVolume(volumeID)
+		})
+
+		_, err = d.waitForVolumeStatus(volumeID, "available")
+		if err != nil {
+			return err
+		}
+
+		log.Debug("Attaching OVH volume...", map[string]interface{}{
+			"VolumeID":  volumeID,
+			"MachineID": d.InstanceID,
+		})
+		err = client.AttachVolume(d.ProjectID, volumeID, d.InstanceID)
+		if err != nil {
+			return err
+		}
+
+		_, err = d.waitForVolumeStatus(volumeID, "in-use")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// detachVolumes detaches every volume of the machine and waits until they are available
+func (d *Driver) detachVolumes() error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	for _, volumeID := range d.VolumeIDs {
+		volume, err := client.GetVolume(d.ProjectID, volumeID)
+		if IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, instanceID := range volume.AttachedTo {
+			if instanceID != d.InstanceID {
+				continue
+			}
+
+			log.Debug("Detaching OVH volume...", map[string]interface{}{
+				"VolumeID":  volumeID,
+				"MachineID": d.InstanceID,
+			})
+			err = client.DetachVolume(d.ProjectID, volumeID, d.InstanceID)
+			if err != nil {
+				return err
+			}
+
+			_, err = d.waitForVolumeStatus(volumeID, "available")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeVolume detaches a volume if needed, deletes it and forgets its id
+func (d *Driver) removeVolume(volumeID string) error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	volume, err := client.GetVolume(d.ProjectID, volumeID)
+	if err != nil && !IsNotFound(err) {
+		return err
+	}
+
+	if volume != nil {
+		for _, instanceID := range volume.AttachedTo {
+			err = client.DetachVolume(d.ProjectID, volumeID, instanceID)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = d.waitForVolumeStatus(volumeID, "available")
+		if err != nil && !IsNotFound(err) {
+			return err
+		}
+	}
+
+	err = client.DeleteVolume(d.ProjectID, volumeID)
+	if err != nil {
+		return err
+	}
+
+	for i, id := range d.VolumeIDs {
+		if id == volumeID {
+			d.VolumeIDs = append(d.VolumeIDs[:i], d.VolumeIDs[i+1:]...)
+			break
+		}
+	}
+	return nil
+}